@@ -7,36 +7,73 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
-	"sync"
 	"time"
 
+	"procfile-runner/export"
+	"procfile-runner/importer"
+	"procfile-runner/supervisor"
+
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// contextLogLines is how many buffered lines are handed to OpenCode as context
+const contextLogLines = 200
+
+// Settings keys of the Procfile auto-reload ("false" turns it off) and of
+// restarting the processes a reload changed ("true" turns it on)
+const (
+	autoReloadSetting    = "autoReload"
+	reloadRestartSetting = "reloadRestart"
+)
+
 // App struct holds the application state
 type App struct {
-	ctx               context.Context
-	processes         map[string]ProcessDefinition
-	running           map[string]*ProcessHandle
-	procfilePath      string
-	globalAutoRestart bool
-	sessionID         string            // unique ID for this session to track orphaned processes
-	envVars           map[string]string // environment variables from .env file
-	initialProcfile   string            // Procfile path passed via CLI argument
-	demoProcfile      string            // embedded demo Procfile content
-	mu                sync.Mutex
+	ctx             context.Context
+	sup             *supervisor.Supervisor
+	control         *controlServer // JSON-RPC control socket for scripts and `ctl`
+	httpAPI         *httpServer    // opt-in HTTP API for local dashboards
+	initialProcfile string         // Procfile path passed via CLI argument
+	demoProcfile    string         // embedded demo Procfile content
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{
-		processes:         make(map[string]ProcessDefinition),
-		running:           make(map[string]*ProcessHandle),
-		globalAutoRestart: true,
-		sessionID:         fmt.Sprintf("%d", time.Now().UnixNano()),
-		envVars:           make(map[string]string),
-	}
+	app := &App{control: newControlServer(), httpAPI: newHTTPServer()}
+	app.sup = supervisor.New(supervisor.MultiSink{wailsSink{app}, app.control, app.httpAPI})
+	return app
+}
+
+// wailsSink forwards supervisor events to the frontend
+type wailsSink struct {
+	app *App
+}
+
+// OnStatus emits process-status
+func (w wailsSink) OnStatus(status supervisor.ProcessStatus) {
+	wailsRuntime.EventsEmit(w.app.ctx, "process-status", status)
+}
+
+// OnOutput emits process-output
+func (w wailsSink) OnOutput(output supervisor.ProcessOutput) {
+	wailsRuntime.EventsEmit(w.app.ctx, "process-output", output)
+}
+
+// OnProcfileLoaded emits procfile-loaded
+func (w wailsSink) OnProcfileLoaded(loaded supervisor.ProcfileLoaded) {
+	wailsRuntime.EventsEmit(w.app.ctx, "procfile-loaded", loaded)
+}
+
+// OnScaled emits process-scaled
+func (w wailsSink) OnScaled(scaled supervisor.ProcessScaled) {
+	wailsRuntime.EventsEmit(w.app.ctx, "process-scaled", scaled)
+}
+
+// OnProcfileReloaded emits procfile-reloaded
+func (w wailsSink) OnProcfileReloaded(reloaded supervisor.ProcfileReloaded) {
+	wailsRuntime.EventsEmit(w.app.ctx, "procfile-reloaded", reloaded)
 }
 
 // startup is called when the app starts. The context is saved
@@ -44,7 +81,30 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	// Kill any orphaned processes from previous sessions
-	a.killOrphanedProcesses()
+	a.sup.KillOrphans()
+
+	// Let scripts and `procfile-runner ctl` drive this session
+	if path, err := getControlSocketPath(); err == nil {
+		if err := a.control.listen(path, a.sup); err != nil {
+			println("Control socket disabled:", err.Error())
+		}
+	}
+
+	// Serve the HTTP API when a port is configured in the settings
+	if setting := GetSettings()[httpPortSetting]; setting != "" {
+		port, err := strconv.Atoi(setting)
+		if err != nil {
+			println("HTTP API disabled: invalid port", setting)
+		} else if note, err := startHTTPAPI(a.httpAPI, port, a.sup); err != nil {
+			println("HTTP API disabled:", err.Error())
+		} else {
+			println(note)
+		}
+	}
+
+	// Reload the Procfile when it changes on disk unless turned off
+	settings := GetSettings()
+	a.sup.SetAutoReload(settings[autoReloadSetting] != "false", settings[reloadRestartSetting] == "true")
 
 	// Load initial Procfile if specified via CLI argument
 	if a.initialProcfile != "" {
@@ -61,150 +121,208 @@ func (a *App) startup(ctx context.Context) {
 // shutdown is called when the app is closing
 func (a *App) shutdown(ctx context.Context) {
 	// Stop all running processes
-	a.StopAllProcesses()
-}
-
-// OpenFileDialog opens a native file dialog for selecting a Procfile
-func (a *App) OpenFileDialog() (string, error) {
-	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
-		Title: "Select Procfile",
-	})
+	a.control.close()
+	a.httpAPI.close()
+	a.sup.Close()
 }
 
 // LoadProcfile loads and parses a Procfile
 func (a *App) LoadProcfile(path string) error {
-	content, err := os.ReadFile(path)
-	if err != nil {
-		return err
-	}
+	return a.sup.Load(path)
+}
 
-	definitions := ParseProcfile(string(content))
+// StartProcess starts a process by type (all its instances) or instance name
+func (a *App) StartProcess(name string) error {
+	return a.sup.Start(name)
+}
 
-	// Load .env file if present
-	envVars := make(map[string]string)
-	envPath := FindEnvFile(path)
-	if envPath != "" {
-		if parsed, err := ParseEnvFile(envPath); err == nil {
-			envVars = parsed
-		}
-	}
+// StopProcess stops a process by type (all its instances) or instance name
+func (a *App) StopProcess(name string) error {
+	return a.sup.Stop(name)
+}
 
-	a.mu.Lock()
-	a.procfilePath = path
-	a.envVars = envVars
-	a.processes = make(map[string]ProcessDefinition)
-	for _, def := range definitions {
-		a.processes[def.Name] = def
-	}
-	a.mu.Unlock()
+// RestartProcess restarts a process by type (all its instances) or instance name
+func (a *App) RestartProcess(name string) error {
+	return a.sup.Restart(name)
+}
 
-	// Get process info for the event
-	processInfos := make([]ProcessInfo, 0, len(definitions))
-	for _, def := range definitions {
-		processInfos = append(processInfos, ProcessInfo{
-			Name:     def.Name,
-			Disabled: def.Disabled,
-		})
-	}
+// StartAllProcesses starts all processes defined in the Procfile in
+// dependency order
+func (a *App) StartAllProcesses() error {
+	return a.sup.StartAll()
+}
 
-	// Emit procfile-loaded event with env info
-	envLoaded := len(envVars) > 0
-	wailsRuntime.EventsEmit(a.ctx, "procfile-loaded", ProcfileLoaded{
-		Path:      path,
-		Processes: processInfos,
-		EnvLoaded: envLoaded,
-		EnvCount:  len(envVars),
-	})
+// StopAllProcesses stops all running processes
+func (a *App) StopAllProcesses() error {
+	return a.sup.StopAll()
+}
 
-	return nil
+// ScaleProcess changes the number of instances of a process type
+func (a *App) ScaleProcess(name string, count int) error {
+	return a.sup.Scale(name, count)
 }
 
-// StartProcess starts a single process by name
-func (a *App) StartProcess(name string) error {
-	a.mu.Lock()
-	def, exists := a.processes[name]
-	a.mu.Unlock()
+// SignalProcess sends a signal to a running process group, or only its
+// leader when leaderOnly is set
+func (a *App) SignalProcess(name string, signal string, leaderOnly bool) error {
+	return a.sup.Signal(name, signal, leaderOnly)
+}
 
-	if !exists {
-		return nil // Process not found, not an error for frontend
-	}
+// WriteStdin writes data to the stdin of a running process instance
+func (a *App) WriteStdin(name string, data string) error {
+	return a.sup.WriteStdin(name, data)
+}
 
-	return a.spawnProcess(name, def)
+// CloseStdin sends EOF to a running process instance
+func (a *App) CloseStdin(name string) error {
+	return a.sup.CloseStdin(name)
 }
 
-// StopProcess stops a single process by name
-func (a *App) StopProcess(name string) error {
-	return a.stopProcess(name)
+// GetLogs returns buffered output of a process, see Supervisor.Logs
+func (a *App) GetLogs(name string, sinceSeq uint64, limit int) []supervisor.ProcessOutput {
+	return a.sup.Logs(name, sinceSeq, limit)
 }
 
-// RestartProcess restarts a single process by name
-func (a *App) RestartProcess(name string) error {
-	a.mu.Lock()
-	def, exists := a.processes[name]
-	a.mu.Unlock()
+// SearchLogs searches the buffered output by text or regular expression
+func (a *App) SearchLogs(query string, regex bool, names []string) ([]supervisor.ProcessOutput, error) {
+	return a.sup.SearchLogs(query, regex, names)
+}
 
-	if !exists {
-		return nil
-	}
+// GetEffectiveEnv returns the environment of a process with the source of
+// every value, see Supervisor.EffectiveEnv
+func (a *App) GetEffectiveEnv(name string) ([]supervisor.EnvVar, error) {
+	return a.sup.EffectiveEnv(name)
+}
 
-	// Stop if running
-	a.stopProcess(name)
+// ClearLogs drops the buffered output of a process, or of all when name is empty
+func (a *App) ClearLogs(name string) {
+	a.sup.ClearLogs(name)
+}
 
-	// Small delay
-	// time.Sleep(500 * time.Millisecond)
+// ListLogFiles returns the log files of the loaded project
+func (a *App) ListLogFiles() ([]supervisor.LogFileInfo, error) {
+	return a.sup.ListLogFiles()
+}
 
-	// Start again
-	return a.spawnProcess(name, def)
+// OpenLogFile opens a log file of the loaded project in the configured
+// editor, or the system default app
+func (a *App) OpenLogFile(path string) error {
+	path, err := a.sup.ReadableLogFile(path)
+	if err != nil {
+		return err
+	}
+
+	if GetSettings()["textEditor"] != "" {
+		return OpenFileInEditor(path, 1)
+	}
+	if runtime.GOOS == "darwin" {
+		return exec.Command("open", path).Run()
+	}
+	return exec.Command("xdg-open", path).Run()
 }
 
-// StartAllProcesses starts all processes defined in the Procfile
-func (a *App) StartAllProcesses() error {
-	a.mu.Lock()
-	definitions := make([]ProcessDefinition, 0, len(a.processes))
-	for _, def := range a.processes {
-		definitions = append(definitions, def)
+// SetGlobalAutoRestart sets the global auto-restart setting
+func (a *App) SetGlobalAutoRestart(enabled bool) {
+	a.sup.SetAutoRestart(enabled)
+}
+
+// SetAutoReload turns reloading the Procfile and .env on file changes on or
+// off, optionally restarting the processes that changed, and remembers it
+func (a *App) SetAutoReload(enabled bool, restartChanged bool) error {
+	a.sup.SetAutoReload(enabled, restartChanged)
+	if err := SaveSetting(autoReloadSetting, strconv.FormatBool(enabled)); err != nil {
+		return err
 	}
-	a.mu.Unlock()
+	return SaveSetting(reloadRestartSetting, strconv.FormatBool(restartChanged))
+}
 
-	for _, def := range definitions {
-		// Skip if already running
-		a.mu.Lock()
-		_, running := a.running[def.Name]
-		a.mu.Unlock()
+// SetTerminalSize sets the window size of processes running in PTY mode
+func (a *App) SetTerminalSize(cols int, rows int) {
+	a.sup.SetTerminalSize(cols, rows)
+}
 
-		if running {
-			continue
-		}
+// OpenFileDialog opens a native file dialog for selecting a Procfile
+func (a *App) OpenFileDialog() (string, error) {
+	return wailsRuntime.OpenFileDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Select Procfile",
+	})
+}
 
-		if err := a.spawnProcess(def.Name, def); err != nil {
-			return err
-		}
+// ExportProcfile writes the loaded Procfile as systemd units, a supervisord
+// config or a docker-compose file into a directory the user picks, and
+// returns the written paths (none when the dialog was cancelled)
+func (a *App) ExportProcfile(format string) ([]string, error) {
+	files, err := export.Generate(format, "", a.sup.Plan())
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	dir, err := wailsRuntime.OpenDirectoryDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title:                "Export to directory",
+		DefaultDirectory:     filepath.Dir(a.sup.ProcfilePath()),
+		CanCreateDirectories: true,
+	})
+	if err != nil || dir == "" {
+		return nil, err
+	}
+	return export.Write(dir, files)
 }
 
-// StopAllProcesses stops all running processes
-func (a *App) StopAllProcesses() error {
-	a.mu.Lock()
-	names := make([]string, 0, len(a.running))
-	for name := range a.running {
-		names = append(names, name)
+// OpenDirectoryDialog opens a native dialog for selecting a project directory
+func (a *App) OpenDirectoryDialog() (string, error) {
+	return wailsRuntime.OpenDirectoryDialog(a.ctx, wailsRuntime.OpenDialogOptions{
+		Title: "Select project folder",
+	})
+}
+
+// ProjectScan is what ScanProject found for a picked file or directory
+type ProjectScan struct {
+	Dir        string               `json:"dir"`
+	Procfile   string               `json:"procfile"`   // Procfile to load, empty when there is none yet
+	Candidates []importer.Candidate `json:"candidates"` // processes found when there is no Procfile
+	Proposal   string               `json:"proposal"`   // proposed Procfile content when there is none
+}
+
+// ScanProject resolves what to load for a path picked in the open dialogs:
+// a Procfile (or variant) as is, otherwise the Procfile of its directory.
+// Without one it proposes a Procfile from the project's package.json,
+// Makefile, docker-compose services and Procfile.* variants.
+func (a *App) ScanProject(path string) (*ProjectScan, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
 	}
-	a.mu.Unlock()
 
-	for _, name := range names {
-		a.stopProcess(name)
+	scan := &ProjectScan{Dir: path}
+	if !info.IsDir() {
+		scan.Dir = filepath.Dir(path)
+		if strings.HasPrefix(filepath.Base(path), "Procfile") && filepath.Ext(path) != ".json" {
+			scan.Procfile = path
+			return scan, nil
+		}
 	}
 
-	return nil
+	if procfile, err := supervisor.ResolveProcfile(scan.Dir); err == nil {
+		scan.Procfile = procfile
+		return scan, nil
+	}
+
+	scan.Candidates, err = importer.Scan(scan.Dir)
+	if err != nil {
+		return nil, err
+	}
+	scan.Proposal = importer.Propose(scan.Candidates)
+	return scan, nil
 }
 
-// SetGlobalAutoRestart sets the global auto-restart setting
-func (a *App) SetGlobalAutoRestart(enabled bool) {
-	a.mu.Lock()
-	a.globalAutoRestart = enabled
-	a.mu.Unlock()
+// ImportProcfile creates the Procfile of a project directory that has none
+// yet, typically the edited ScanProject proposal, and loads it
+func (a *App) ImportProcfile(dir string, content string) error {
+	path := filepath.Join(dir, "Procfile")
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	return a.saveProcfile(path, content)
 }
 
 // GetRecentProjects returns the list of recent project paths
@@ -225,8 +343,20 @@ func (a *App) AddRecentProject(path string) []string {
 	return projects
 }
 
-// SaveLog saves log content to a tmp file and returns the file path
+// SaveLog saves log content to a tmp file and returns the file path. When
+// content is empty the buffered output of the process is saved. Secrets are
+// masked either way.
 func (a *App) SaveLog(processName string, content string) (string, error) {
+	if content == "" {
+		lines := a.GetLogs(processName, 0, 0)
+		if len(lines) == 0 {
+			return "", fmt.Errorf("no logs for process %q", processName)
+		}
+		content = supervisor.FormatLogLines(lines, false)
+	}
+	// Text from the frontend may predate the current redact settings
+	content = a.sup.Redact(content)
+
 	tmpDir := os.TempDir()
 	timestamp := time.Now().Format("2006-01-02_15-04-05")
 	fileName := fmt.Sprintf("procfile-runner_%s_%s.txt", processName, timestamp)
@@ -271,9 +401,7 @@ func (a *App) CheckOpenCode() string {
 
 // EnableProcess enables a disabled process in the Procfile and reloads
 func (a *App) EnableProcess(processName string) error {
-	a.mu.Lock()
-	procfilePath := a.procfilePath
-	a.mu.Unlock()
+	procfilePath := a.sup.ProcfilePath()
 
 	if procfilePath == "" {
 		return fmt.Errorf("no Procfile loaded")
@@ -287,8 +415,18 @@ func (a *App) EnableProcess(processName string) error {
 	return a.LoadProcfile(procfilePath)
 }
 
-// AskOpenCode opens a new terminal window with OpenCode, passing logs as context
+// AskOpenCode opens a new terminal window with OpenCode, passing logs as
+// context. When logs is empty the last 200 buffered lines of the process are used.
 func (a *App) AskOpenCode(processName string, logs string, question string) error {
+	if logs == "" {
+		lines := a.GetLogs(processName, 0, contextLogLines)
+		if len(lines) == 0 {
+			return fmt.Errorf("no logs for process %q", processName)
+		}
+		logs = supervisor.FormatLogLines(lines, true)
+	}
+	logs = a.sup.Redact(logs)
+
 	// Check if opencode is installed
 	if _, err := exec.LookPath("opencode"); err != nil {
 		// opencode not installed, open website
@@ -387,9 +525,12 @@ func (a *App) AskOpenCode(processName string, logs string, question string) erro
 
 // GetProcfileContent returns the raw content of the currently loaded Procfile
 func (a *App) GetProcfileContent() (string, error) {
-	a.mu.Lock()
-	path := a.procfilePath
-	a.mu.Unlock()
+	return procfileContent(a.sup)
+}
+
+// procfileContent returns the raw content of the Procfile loaded into sup
+func procfileContent(sup *supervisor.Supervisor) (string, error) {
+	path := sup.ProcfilePath()
 
 	if path == "" {
 		return "", fmt.Errorf("no Procfile loaded")
@@ -405,14 +546,17 @@ func (a *App) GetProcfileContent() (string, error) {
 
 // SaveProcfileContent saves content to the currently loaded Procfile and reloads it
 func (a *App) SaveProcfileContent(content string) error {
-	a.mu.Lock()
-	path := a.procfilePath
-	a.mu.Unlock()
+	path := a.sup.ProcfilePath()
 
 	if path == "" {
 		return fmt.Errorf("no Procfile loaded")
 	}
 
+	return a.saveProcfile(path, content)
+}
+
+// saveProcfile writes a Procfile and loads it
+func (a *App) saveProcfile(path string, content string) error {
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		return err
 	}
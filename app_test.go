@@ -1,88 +1,19 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
-)
-
-func TestParseProcfile(t *testing.T) {
-	content := `# Test Procfile
-alpha: echo A
-beta: echo B
-gamma: echo C
-`
-	defs := ParseProcfile(content)
-
-	if len(defs) != 3 {
-		t.Errorf("Expected 3 definitions, got %d", len(defs))
-	}
-
-	expected := map[string]string{
-		"alpha": "echo A",
-		"beta":  "echo B",
-		"gamma": "echo C",
-	}
-
-	for _, def := range defs {
-		if cmd, ok := expected[def.Name]; ok {
-			if def.Command != cmd {
-				t.Errorf("Expected command %q for %s, got %q", cmd, def.Name, def.Command)
-			}
-		} else {
-			t.Errorf("Unexpected process: %s", def.Name)
-		}
-	}
-}
-
-func TestParseProcfileSkipsComments(t *testing.T) {
-	content := `# This is a comment
-web: echo web
-# Another comment
-worker: echo worker
-`
-	defs := ParseProcfile(content)
 
-	if len(defs) != 2 {
-		t.Errorf("Expected 2 definitions (comments skipped), got %d", len(defs))
-	}
-}
-
-func TestParseProcfileSkipsEmptyLines(t *testing.T) {
-	content := `
-web: echo web
-
-worker: echo worker
-
-`
-	defs := ParseProcfile(content)
-
-	if len(defs) != 2 {
-		t.Errorf("Expected 2 definitions (empty lines skipped), got %d", len(defs))
-	}
-}
-
-func TestGetParentDir(t *testing.T) {
-	tests := []struct {
-		input    string
-		expected string
-	}{
-		{"/Users/test/project/Procfile", "/Users/test/project"},
-		{"/Procfile", ""},
-		{"Procfile", "."},
-		{"/a/b/c/d", "/a/b/c"},
-	}
-
-	for _, tt := range tests {
-		result := getParentDir(tt.input)
-		if result != tt.expected {
-			t.Errorf("getParentDir(%q) = %q, expected %q", tt.input, result, tt.expected)
-		}
-	}
-}
+	"procfile-runner/supervisor"
+)
 
 func TestRecentProjects(t *testing.T) {
 	// Create temp config dir
@@ -133,78 +64,494 @@ func TestRecentProjects(t *testing.T) {
 	}
 }
 
-// TestProcessSpawnAndStop tests spawning and stopping processes
-func TestProcessSpawnAndStop(t *testing.T) {
-	app := NewApp()
+// TestCLIStart runs processes headless until they exit on their own
+func TestCLIStart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("ok: echo hello $GREETING\nfail: echo oops; exit 3\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("GREETING=world\n"), 0644)
+
+	var out strings.Builder
+	printer := newCLIPrinter(&out, false)
+	sup := supervisor.New(printer)
+	sup.SetSessionID("")
+	sup.SetAutoRestart(false)
+	if err := sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := cliStart(sup, printer, []string{"ok"}); code != exitOK {
+		t.Errorf("Expected exit code %d, got %d:\n%s", exitOK, code, out.String())
+	}
+	if !strings.Contains(out.String(), "ok     | hello world") {
+		t.Errorf("Expected prefixed output, got:\n%s", out.String())
+	}
+
+	if code := cliStart(sup, printer, nil); code != exitFailed {
+		t.Errorf("Expected exit code %d after a failed process, got %d:\n%s", exitFailed, code, out.String())
+	}
+	if !strings.Contains(out.String(), "fail exited with code 3") {
+		t.Errorf("Expected exit status line, got:\n%s", out.String())
+	}
+}
+
+// TestCLIRun runs a Procfile entry with the environment of its process
+func TestCLIRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
 
-	// Create a simple procfile
-	procContent := "test: while true; do echo TEST; sleep 0.1; done"
-	defs := ParseProcfile(procContent)
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("web: echo \"$GREETING $NAME $PORT $PS\" > out.txt\n"), 0644)
+	os.WriteFile(procfilePath+".json", []byte(`{"processes": {"web": {"env": {"NAME": "web-${GREETING}"}}}}`), 0644)
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("GREETING=hello\n"), 0644)
 
-	app.processes = make(map[string]ProcessDefinition)
-	for _, def := range defs {
-		app.processes[def.Name] = def
+	sup := supervisor.New(supervisor.MultiSink{})
+	sup.SetSessionID("")
+	if err := sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
 	}
 
-	// Note: We can't fully test spawn without the Wails context
-	// This is more of a structural test
-	if len(app.processes) != 1 {
-		t.Errorf("Expected 1 process definition, got %d", len(app.processes))
+	if code := cliRun(sup, []string{"web"}); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "out.txt"))
+	if got := strings.TrimSpace(string(data)); got != "hello web-hello 5000 web.1" {
+		t.Errorf("Expected the process's env, PORT and PS, got %q", got)
 	}
 
-	if app.processes["test"].Command != "while true; do echo TEST; sleep 0.1; done" {
-		t.Errorf("Unexpected command: %s", app.processes["test"].Command)
+	if code := cliRun(sup, []string{"exit", "4"}); code != 4 {
+		t.Errorf("Expected the command's exit code 4, got %d", code)
 	}
 }
 
-// Integration test - runs actual processes
-func TestIntegrationProcessLifecycle(t *testing.T) {
+// TestControlSocket drives a session through the control socket
+func TestControlSocket(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping integration test in short mode")
 	}
 
-	// This test requires manual verification or a mock context
-	// For now, we'll just test the parsing and config parts
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("ticker: while true; do echo tick; sleep 0.1; done\n"), 0644)
 
-	procfile := `
-alpha: while true; do echo "A"; sleep 0.5; done
-beta: while true; do echo "B"; sleep 0.5; done
-gamma: while true; do echo "C"; sleep 0.5; done
-delta: while true; do echo "D"; sleep 0.5; done
-epsilon: while true; do echo "E"; sleep 0.5; done
-`
-	defs := ParseProcfile(procfile)
+	control := newControlServer()
+	sup := supervisor.New(supervisor.MultiSink{supervisor.NewRecorder(), control})
+	sup.SetSessionID("")
+	if err := sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Close()
 
-	if len(defs) != 5 {
-		t.Errorf("Expected 5 process definitions, got %d", len(defs))
+	socketDir := filepath.Join(dir, "config")
+	os.Mkdir(socketDir, 0755)
+	socketPath := filepath.Join(socketDir, controlSocketName)
+	if err := control.listen(socketPath, sup); err != nil {
+		t.Fatal(err)
 	}
+	defer control.close()
 
-	expectedNames := []string{"alpha", "beta", "gamma", "delta", "epsilon"}
-	for i, def := range defs {
-		if def.Name != expectedNames[i] {
-			t.Errorf("Expected name %s at position %d, got %s", expectedNames[i], i, def.Name)
+	if info, err := os.Stat(socketPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected socket mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if info, err := os.Stat(socketDir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected the socket dir to be made private, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if err := newControlServer().listen(socketPath, sup); err == nil {
+		t.Error("Expected a second server on the same socket to fail")
+	}
+
+	client, err := dialControl(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+
+	outputs := make(chan supervisor.ProcessOutput, 100)
+	client.onNotify = func(method string, params json.RawMessage) {
+		var output supervisor.ProcessOutput
+		if method == "process-output" && json.Unmarshal(params, &output) == nil {
+			select {
+			case outputs <- output:
+			default:
+			}
+		}
+	}
+	if err := client.call("Subscribe", map[string][]string{"events": {"process-output"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := ctlCommand(client, &out, "start", []string{"ticker"}); err != nil {
+		t.Fatal(err)
+	}
+	go client.follow()
+	select {
+	case output := <-outputs:
+		if output.Name != "ticker" || output.Line != "tick" {
+			t.Errorf("Unexpected output notification: %+v", output)
 		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected an output notification")
+	}
+
+	// follow owns the connection now; use a second client for commands
+	commands, err := dialControl(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer commands.close()
+
+	if err := ctlCommand(commands, &out, "status", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "ticker  running") {
+		t.Errorf("Expected ticker running in status, got:\n%s", out.String())
+	}
+
+	err = commands.call("StartProcess", nil, nil)
+	if rpcErr, ok := err.(*rpcError); !ok || rpcErr.Code != rpcInvalidParams {
+		t.Errorf("Expected invalid params error, got %v", err)
+	}
+	err = commands.call("Explode", nil, nil)
+	if rpcErr, ok := err.(*rpcError); !ok || rpcErr.Code != rpcMethodNotFound {
+		t.Errorf("Expected method not found error, got %v", err)
+	}
+
+	if err := ctlCommand(commands, &out, "stop", nil); err != nil {
+		t.Fatal(err)
+	}
+	var statuses []supervisor.ProcessStatus
+	if err := commands.call("GetStatus", nil, &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Status != "stopped" {
+		t.Errorf("Expected ticker stopped, got %+v", statuses)
 	}
 }
 
-// TestSessionID verifies that session IDs are unique
-func TestSessionID(t *testing.T) {
-	app1 := NewApp()
-	time.Sleep(time.Millisecond)
-	app2 := NewApp()
+// TestControlSocketInput signals processes and writes to their stdin
+// through the control socket
+func TestControlSocketInput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("echo: cat\nwaiter: trap 'echo got HUP' HUP; echo ready; while true; do sleep 0.1; done\n"), 0644)
 
-	if app1.sessionID == app2.sessionID {
-		t.Error("Session IDs should be unique")
+	recorder := supervisor.NewRecorder()
+	control := newControlServer()
+	sup := supervisor.New(supervisor.MultiSink{recorder, control})
+	sup.SetSessionID("")
+	if err := sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Close()
+
+	socketPath := filepath.Join(dir, controlSocketName)
+	if err := control.listen(socketPath, sup); err != nil {
+		t.Fatal(err)
+	}
+	defer control.close()
+
+	client, err := dialControl(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.close()
+
+	if err := client.call("StartAllProcesses", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"echo", "waiter"} {
+		if _, ok := recorder.WaitStatus(name, "running", 1, 5*time.Second); !ok {
+			t.Fatalf("Expected %s to start", name)
+		}
+	}
+	// The trap is set once waiter prints ready
+	if !recorder.WaitLine("waiter", "ready", 5*time.Second) {
+		t.Fatalf("Expected waiter to get ready, got %q", recorder.Lines("waiter"))
+	}
+
+	if err := client.call("WriteStdin", map[string]string{"name": "echo", "data": "hello\n"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.WaitLine("echo", "hello", 5*time.Second) {
+		t.Errorf("Expected cat to echo the input, got %q", recorder.Lines("echo"))
+	}
+	if err := client.call("CloseStdin", map[string]string{"name": "echo"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := recorder.WaitStatus("echo", "stopped", 1, 5*time.Second); !ok {
+		t.Error("Expected cat to exit at EOF")
+	}
+
+	if err := client.call("SignalProcess", map[string]interface{}{"name": "waiter", "signal": "HUP", "leader_only": true}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !recorder.WaitLine("waiter", "got HUP", 5*time.Second) {
+		t.Errorf("Expected the signal to reach the process, got %q", recorder.Lines("waiter"))
+	}
+
+	err = client.call("SignalProcess", map[string]string{"name": "waiter"}, nil)
+	if rpcErr, ok := err.(*rpcError); !ok || rpcErr.Code != rpcInvalidParams {
+		t.Errorf("Expected invalid params error for a missing signal, got %v", err)
+	}
+	if err := client.call("WriteStdin", map[string]string{"name": "echo", "data": "late\n"}, nil); err == nil {
+		t.Error("Expected writing to a stopped process to fail")
+	}
+}
+
+// TestHTTPAPI drives a session over HTTP and follows its event stream
+func TestHTTPAPI(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("ticker: while true; do echo tick; sleep 0.1; done\n"), 0644)
+
+	tokenPath := filepath.Join(dir, httpTokenName)
+	token, err := loadHTTPToken(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(tokenPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected token file mode 0600, got %v (%v)", info.Mode().Perm(), err)
+	}
+	if again, _ := loadHTTPToken(tokenPath); again != token {
+		t.Error("Expected the stored token to be reused")
+	}
+
+	api := newHTTPServer()
+	sup := supervisor.New(supervisor.MultiSink{supervisor.NewRecorder(), api})
+	sup.SetSessionID("")
+	if err := sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Close()
+	api.sup = sup
+	api.token = token
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	request := func(method string, path string, auth string) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", "Bearer "+auth)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := request("GET", "/api/processes", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", resp.StatusCode)
+	}
+	if resp := request("GET", "/api/processes", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a wrong token, got %d", resp.StatusCode)
+	}
+	if resp := request("GET", "/api/processes?token="+token, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a query token outside the event stream, got %d", resp.StatusCode)
+	}
+
+	// EventSource can't send headers, so the stream takes the token as a query param
+	events, err := http.Get(server.URL + "/api/events?names=ticker&token=" + token)
+	if err != nil || events.StatusCode != http.StatusOK {
+		t.Fatalf("Expected event stream, got %v %v", events, err)
+	}
+	defer events.Body.Close()
+
+	if resp := request("POST", "/api/processes/ticker/start", token); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected start to succeed, got %d", resp.StatusCode)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(events.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	var seen []string
+	timeout := time.After(5 * time.Second)
+	for !strings.Contains(strings.Join(seen, "\n"), "event: process-output\ndata: {") {
+		select {
+		case line := <-lines:
+			seen = append(seen, line)
+		case <-timeout:
+			t.Fatalf("Expected process-output event, got:\n%s", strings.Join(seen, "\n"))
+		}
+	}
+	if !strings.Contains(strings.Join(seen, "\n"), "event: process-status") {
+		t.Errorf("Expected process-status event before output, got:\n%s", strings.Join(seen, "\n"))
+	}
+
+	resp := request("GET", "/api/processes", token)
+	var statuses []supervisor.ProcessStatus
+	json.NewDecoder(resp.Body).Decode(&statuses)
+	if len(statuses) != 1 || statuses[0].Status != "running" {
+		t.Errorf("Expected ticker running, got %+v", statuses)
+	}
+
+	resp = request("GET", "/api/procfile", token)
+	var procfile map[string]string
+	json.NewDecoder(resp.Body).Decode(&procfile)
+	if procfile["path"] != procfilePath || !strings.HasPrefix(procfile["content"], "ticker:") {
+		t.Errorf("Unexpected procfile response: %v", procfile)
+	}
+
+	if resp := request("POST", "/api/processes/nope/signal", token); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a signal without body, got %d", resp.StatusCode)
+	}
+	if resp := request("POST", "/api/processes/ticker/stop", token); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected stop to succeed, got %d", resp.StatusCode)
 	}
 }
 
-// Benchmark parsing
-func BenchmarkParseProcfile(b *testing.B) {
-	content := strings.Repeat("process: echo hello\n", 100)
-	b.ResetTimer()
+// TestMCPServer lists, starts and reads processes through MCP tools
+func TestMCPServer(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping integration test in short mode")
+	}
+
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("greeter: echo hello from greeter; sleep 30\n"), 0644)
+
+	recorder := supervisor.NewRecorder()
+	sup := supervisor.New(recorder)
+	sup.SetSessionID("")
+	if err := sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer sup.Close()
+	server := &mcpServer{backend: localBackend{sup: sup}}
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"start_process","arguments":{"name":"greeter"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"restart_process","arguments":{"name":"nope"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"bogus"}}`,
+	}, "\n")
+	var out strings.Builder
+	if err := server.serve(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(responses) != 5 {
+		t.Fatalf("Expected 5 responses (none for the notification), got:\n%s", out.String())
+	}
+	expected := []string{
+		`"protocolVersion":"2024-11-05"`,
+		`"name":"restart_process"`,
+		`greeter: start requested`,
+		`"isError":true`,
+		`"code":-32602`,
+	}
+	for i, want := range expected {
+		if !strings.Contains(responses[i], want) {
+			t.Errorf("Response %d: expected %s, got %s", i+1, want, responses[i])
+		}
+	}
+
+	if !strings.Contains(responses[1], `"names":{`) {
+		t.Errorf("Expected read_logs to advertise names, got %s", responses[1])
+	}
+
+	if !recorder.WaitLine("greeter", "hello from greeter", 5*time.Second) {
+		t.Fatal("Expected greeter output")
+	}
+	text, err := server.callTool("read_logs", mcpArguments{Name: "greeter"})
+	if err != nil || !strings.Contains(text, "hello from greeter") {
+		t.Errorf("Expected log history, got %q (%v)", text, err)
+	}
+	text, err = server.callTool("read_logs", mcpArguments{Query: "hello", Names: []string{"greeter"}})
+	if err != nil || !strings.Contains(text, "hello from greeter") {
+		t.Errorf("Expected a search of greeter's logs, got %q (%v)", text, err)
+	}
+	text, err = server.callTool("list_processes", mcpArguments{})
+	if err != nil || !strings.Contains(text, `"status": "running"`) {
+		t.Errorf("Expected greeter running, got %q (%v)", text, err)
+	}
+	text, err = server.callTool("read_procfile", mcpArguments{})
+	if err != nil || !strings.HasPrefix(text, "greeter:") {
+		t.Errorf("Expected Procfile content, got %q (%v)", text, err)
+	}
+}
+
+func TestTUI(t *testing.T) {
+	dir := t.TempDir()
+	procfilePath := filepath.Join(dir, "Procfile")
+	os.WriteFile(procfilePath, []byte("web: sleep 30\nworker: sleep 30\n"), 0644)
+
+	ui := newTUI()
+	ui.sup = supervisor.New(ui)
+	ui.sup.SetSessionID("")
+	if err := ui.sup.Load(procfilePath); err != nil {
+		t.Fatal(err)
+	}
+	defer ui.sup.Close()
+
+	ui.OnOutput(supervisor.ProcessOutput{Name: "web", Line: "\x1b[32mGET /health\x1b[0m 200"})
+	ui.OnOutput(supervisor.ProcessOutput{Name: "worker", Line: "job done"})
+	ui.OnOutput(supervisor.ProcessOutput{Name: "web", Line: "GET /users 500", IsStderr: true})
+
+	frame := ui.frame()
+	for _, text := range []string{"web", "worker", "stopped", "GET /health", "job done", "0/2 running"} {
+		if !strings.Contains(frame, text) {
+			t.Errorf("Expected frame to contain %q", text)
+		}
+	}
+
+	// Selecting worker filters the log pane
+	ui.handleKey("down")
+	ui.handleKey("j")
+	frame = ui.frame()
+	if ui.filter != "worker" || strings.Contains(frame, "GET /health") || !strings.Contains(frame, "job done") {
+		t.Errorf("Expected only worker output, filter %q", ui.filter)
+	}
+
+	// Search is case-insensitive and ignores colors
+	ui.handleKey("k")
+	ui.handleKey("k")
+	for _, key := range parseKeys([]byte("/get /H\r")) {
+		ui.handleKey(key)
+	}
+	frame = ui.frame()
+	if ui.search != "get /H" || !strings.Contains(frame, "GET /health") || strings.Contains(frame, "GET /users") {
+		t.Errorf("Expected only the matching line for search %q", ui.search)
+	}
+	ui.handleKey("esc")
+	if ui.search != "" {
+		t.Errorf("Expected Esc to clear the search, got %q", ui.search)
+	}
+
+	if ui.handleKey("s") || !strings.Contains(ui.message, "Select a process") {
+		t.Errorf("Expected s on All to ask for a process, got %q", ui.message)
+	}
+	if !ui.handleKey("q") {
+		t.Error("Expected q to quit")
+	}
 
-	for i := 0; i < b.N; i++ {
-		ParseProcfile(content)
+	keys := parseKeys([]byte("\x1b[A\x1b[B\x1b[5~\x1b[6~\x1bOH\x1b[4~\x1b\x7f\x03é"))
+	expected := []string{"up", "down", "pgup", "pgdn", "home", "end", "esc", "backspace", "ctrl-c", "é"}
+	if strings.Join(keys, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected keys %v, got %v", expected, keys)
 	}
 }
 
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"procfile-runner/export"
+	"procfile-runner/supervisor"
+)
+
+// Exit codes of the headless commands
+const (
+	exitOK      = 0 // all processes succeeded or were stopped on request
+	exitFailed  = 1 // a process failed, crashed or could not be started
+	exitUsage   = 2 // bad arguments or an invalid Procfile
+	cliInterval = 100 * time.Millisecond
+)
+
+// cliColors are the ANSI colors used for process name prefixes, like foreman
+var cliColors = []string{"36", "33", "32", "35", "34", "31", "96", "93", "92", "95", "94", "91"}
+
+const cliUsage = `Usage: procfile-runner [Procfile]            open the desktop app
+       procfile-runner start [-f Procfile] [--no-restart] [--watch] [--http-port N] [name...]
+                                             run processes in the terminal
+       procfile-runner tui [-f Procfile] [--watch]
+                                             interactive terminal UI
+       procfile-runner run [-f Procfile] <name|command...>
+                                             run one command with the Procfile env
+       procfile-runner check [-f Procfile]   validate the Procfile and its options
+       procfile-runner export [-f Procfile] [--app name] [--no-restart] <systemd|supervisord|compose> <dir>
+                                             write the Procfile as config for another process manager
+       procfile-runner ctl <command> [args]  control a running session (ctl help)
+       procfile-runner mcp [-f Procfile]     serve MCP tools for AI agents on stdio
+`
+
+// isCLICommand reports whether arg is a headless subcommand
+func isCLICommand(arg string) bool {
+	switch arg {
+	case "start", "tui", "run", "check", "export", "ctl", "mcp", "help":
+		return true
+	}
+	return false
+}
+
+// runCLI runs a headless subcommand and returns the process exit code
+func runCLI(args []string) int {
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	procfile := flags.String("f", "Procfile", "path to the Procfile")
+	noRestart := flags.Bool("no-restart", false, "never restart processes that exit")
+	watch := flags.Bool("watch", false, "reload the Procfile and .env on change, restarting changed processes")
+	httpPort := flags.Int("http-port", 0, "serve the HTTP API on 127.0.0.1:port")
+	appName := flags.String("app", "", "export: name prefix of units and programs (default: the Procfile directory)")
+
+	if command == "help" {
+		fmt.Print(cliUsage)
+		return exitOK
+	}
+	if command == "ctl" {
+		return runCtl(args)
+	}
+	if command == "mcp" {
+		return runMCP(args)
+	}
+	if command == "tui" {
+		return runTUI(args)
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fmt.Print(cliUsage)
+			return exitOK
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n\n%s", command, err, cliUsage)
+		return exitUsage
+	}
+
+	path, err := filepath.Abs(*procfile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	printer := newCLIPrinter(os.Stdout, isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "")
+	control := newControlServer()
+	httpAPI := newHTTPServer()
+	sup := supervisor.New(supervisor.MultiSink{printer, control, httpAPI})
+	// Headless sessions are not tagged, so the desktop app doesn't treat
+	// their processes as orphans
+	sup.SetSessionID("")
+	sup.SetAutoRestart(!*noRestart)
+
+	if err := sup.Load(path); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *procfile, err)
+		return exitUsage
+	}
+
+	switch command {
+	case "check":
+		return cliCheck(sup, path)
+	case "run":
+		return cliRun(sup, flags.Args())
+	case "export":
+		return cliExport(sup, *appName, flags.Args())
+	default:
+		if *watch {
+			sup.SetAutoReload(true, true)
+		}
+		// Let `procfile-runner ctl` drive this session too
+		if path, err := getControlSocketPath(); err == nil {
+			if err := control.listen(path, sup); err != nil {
+				printer.system(fmt.Sprintf("Control socket disabled: %v", err))
+			}
+			defer control.close()
+		}
+		if *httpPort > 0 {
+			note, err := startHTTPAPI(httpAPI, *httpPort, sup)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "start: HTTP API: %v\n", err)
+				return exitUsage
+			}
+			defer httpAPI.close()
+			printer.system(note)
+		}
+		return cliStart(sup, printer, flags.Args())
+	}
+}
+
+// cliCheck reports what was found in the Procfile; loading it already validated it
+func cliCheck(sup *supervisor.Supervisor, path string) int {
+	var active, disabled []string
+	for _, def := range sup.Definitions() {
+		if def.Disabled {
+			disabled = append(disabled, def.Name)
+		} else {
+			active = append(active, def.Name)
+		}
+	}
+
+	if len(active) == 0 {
+		fmt.Fprintf(os.Stderr, "%s: no processes defined\n", path)
+		return exitUsage
+	}
+
+	fmt.Printf("valid procfile detected (%s)\n", strings.Join(active, ", "))
+	if len(disabled) > 0 {
+		fmt.Printf("disabled: %s\n", strings.Join(disabled, ", "))
+	}
+
+	for _, file := range sup.EnvFiles() {
+		fmt.Printf("%d env vars from %s\n", file.Count, file.Path)
+	}
+
+	return exitOK
+}
+
+// cliExport writes the loaded Procfile as another process manager's config
+func cliExport(sup *supervisor.Supervisor, app string, args []string) int {
+	if len(args) != 2 {
+		fmt.Fprintf(os.Stderr, "export: expected a format (%s) and a directory\n\n%s", strings.Join(export.Formats, ", "), cliUsage)
+		return exitUsage
+	}
+
+	files, err := export.Generate(args[0], app, sup.Plan())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return exitUsage
+	}
+	paths, err := export.Write(args[1], files)
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export: %v\n", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// cliRun runs a Procfile entry or an arbitrary command in the foreground
+// and exits with its exit code. An entry gets the environment its process
+// would get under the supervisor, a command the Procfile's environment.
+func cliRun(sup *supervisor.Supervisor, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "run: missing command\n\n%s", cliUsage)
+		return exitUsage
+	}
+
+	command := strings.Join(args, " ")
+	env := sup.Env()
+	for _, def := range sup.Definitions() {
+		if def.Name != command {
+			continue
+		}
+		processEnv, err := sup.ProcessEnv(def.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "run: %v\n", err)
+			return exitFailed
+		}
+		command, env = def.Command, processEnv
+		break
+	}
+
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Dir = filepath.Dir(sup.ProcfilePath())
+	cmd.Env = env
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	// The command shares our terminal and gets Ctrl-C itself
+	signal.Ignore(os.Interrupt, syscall.SIGQUIT)
+
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+				return 128 + int(status.Signal())
+			}
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// cliStart runs the given process types (all enabled ones when none are
+// given, plus their dependencies) until they exit or Ctrl-C is pressed
+func cliStart(sup *supervisor.Supervisor, printer *cliPrinter, names []string) int {
+	if len(names) == 0 {
+		for _, def := range sup.Definitions() {
+			if !def.Disabled {
+				names = append(names, def.Name)
+			}
+		}
+	}
+	selected, err := sup.WithDependencies(names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "start: %v\n", err)
+		return exitUsage
+	}
+
+	var instances []string
+	for _, name := range selected {
+		instances = append(instances, sup.Instances(name)...)
+	}
+	printer.setNames(instances)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	started := make(chan error, 1)
+	go func() { started <- sup.StartTypes(selected) }()
+
+	stopped := make(chan struct{})
+	stopping := false
+	startDone := false
+	ticker := time.NewTicker(cliInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case sig := <-signals:
+			if stopping {
+				// Second Ctrl-C: don't wait for graceful shutdown
+				printer.system(fmt.Sprintf("%s received again, killing all processes", supervisor.SignalName(sig.(syscall.Signal))))
+				sup.ForceStop()
+				continue
+			}
+			stopping = true
+			printer.setStopping()
+			printer.system(fmt.Sprintf("%s received, stopping all processes (press Ctrl-C again to kill)", supervisor.SignalName(sig.(syscall.Signal))))
+			go func() {
+				sup.StopAll()
+				close(stopped)
+			}()
+
+		case err := <-started:
+			startDone = true
+			if err != nil {
+				printer.fail()
+				for _, line := range strings.Split(err.Error(), "\n") {
+					printer.system(line)
+				}
+			}
+
+		case <-stopped:
+			return printer.exitCode()
+
+		case <-ticker.C:
+			// Everything exited on its own and nothing is waiting to restart
+			if startDone && !stopping && sup.Idle() {
+				return printer.exitCode()
+			}
+		}
+	}
+}
+
+// cliPrinter writes foreman-style prefixed output and tracks failures
+type cliPrinter struct {
+	mu       sync.Mutex
+	out      io.Writer
+	color    bool
+	width    int
+	colors   map[string]string
+	failed   bool            // a process could not be started
+	exited   map[string]bool // instances whose last exit was a failure
+	stopping bool
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newCLIPrinter creates a printer writing to out, optionally with colors
+func newCLIPrinter(out io.Writer, color bool) *cliPrinter {
+	return &cliPrinter{
+		out:    out,
+		color:  color,
+		width:  len("system"),
+		colors: make(map[string]string),
+		exited: make(map[string]bool),
+	}
+}
+
+// setNames assigns colors and the prefix width for the process instances
+func (p *cliPrinter) setNames(names []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	sort.Strings(names)
+	for i, name := range names {
+		p.colors[name] = cliColors[i%len(cliColors)]
+		if len(name) > p.width {
+			p.width = len(name)
+		}
+	}
+}
+
+// setStopping marks the shutdown, after which exits are expected
+func (p *cliPrinter) setStopping() {
+	p.mu.Lock()
+	p.stopping = true
+	p.mu.Unlock()
+}
+
+// fail records a failure for the exit code
+func (p *cliPrinter) fail() {
+	p.mu.Lock()
+	p.failed = true
+	p.mu.Unlock()
+}
+
+// setExited records whether the last exit of a process instance was a
+// failure; a successful restart clears it
+func (p *cliPrinter) setExited(name string, failed bool) {
+	p.mu.Lock()
+	p.exited[name] = failed
+	p.mu.Unlock()
+}
+
+// exitCode returns the exit code for the session so far
+func (p *cliPrinter) exitCode() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.failed {
+		return exitFailed
+	}
+	for _, failed := range p.exited {
+		if failed {
+			return exitFailed
+		}
+	}
+	return exitOK
+}
+
+// system prints a line from the runner itself
+func (p *cliPrinter) system(line string) {
+	p.print("system", line)
+}
+
+// print writes a line with the current time and the colored process name
+func (p *cliPrinter) print(name string, line string) {
+	p.printAt(time.Now(), name, line)
+}
+
+// printAt writes a line with a timestamp and the colored process name
+func (p *cliPrinter) printAt(at time.Time, name string, line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if at.IsZero() {
+		at = time.Now()
+	}
+	prefix := fmt.Sprintf("%s %-*s |", at.Format("15:04:05"), p.width, name)
+	if p.color {
+		color := p.colors[name]
+		if color == "" {
+			color = "1" // bold for system and unknown names
+		}
+		prefix = fmt.Sprintf("\x1b[%sm%s\x1b[0m", color, prefix)
+	}
+	fmt.Fprintf(p.out, "%s %s\n", prefix, line)
+}
+
+// OnOutput prints a line of process output
+func (p *cliPrinter) OnOutput(output supervisor.ProcessOutput) {
+	p.printAt(output.Time, output.Name, output.Line)
+}
+
+// OnStatus prints status changes and tracks failed exits
+func (p *cliPrinter) OnStatus(status supervisor.ProcessStatus) {
+	switch status.Status {
+	case "starting", "running":
+		p.setExited(status.Name, false)
+		p.system(fmt.Sprintf("%s started with pid %d", status.Name, status.PID))
+	case "ready":
+		p.system(fmt.Sprintf("%s is ready", status.Name))
+	case "unhealthy":
+		p.system(fmt.Sprintf("%s is not ready yet", status.Name))
+	case "crashed":
+		p.setExited(status.Name, true)
+		p.system(fmt.Sprintf("%s crashed, giving up", status.Name))
+	case "stopped":
+		p.mu.Lock()
+		stopping := p.stopping
+		p.mu.Unlock()
+
+		// Without an exit code the process was stopped on request (Ctrl-C
+		// or `ctl stop`), which is not a failure
+		if status.ExitCode == nil {
+			p.setExited(status.Name, false)
+			p.system(fmt.Sprintf("%s stopped", status.Name))
+			return
+		}
+		if !stopping {
+			p.setExited(status.Name, *status.ExitCode != 0)
+		}
+		p.system(fmt.Sprintf("%s exited with code %d", status.Name, *status.ExitCode))
+	}
+}
+
+// OnProcfileLoaded does nothing; the Procfile is loaded before output starts
+func (p *cliPrinter) OnProcfileLoaded(loaded supervisor.ProcfileLoaded) {}
+
+// OnScaled does nothing; headless sessions don't scale
+func (p *cliPrinter) OnScaled(scaled supervisor.ProcessScaled) {}
+
+// OnProcfileReloaded prints what changed on disk
+func (p *cliPrinter) OnProcfileReloaded(reloaded supervisor.ProcfileReloaded) {
+	p.system(reloaded.Summary())
+}
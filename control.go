@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"procfile-runner/supervisor"
+)
+
+// controlSocketName is the control socket file in the config dir
+const controlSocketName = "control.sock"
+
+// controlQueueSize is how many messages may wait for a slow client before
+// event notifications to it are dropped
+const controlQueueSize = 1024
+
+// JSON-RPC 2.0 error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcFailed         = -32000 // the operation itself returned an error
+)
+
+// rpcRequest is a JSON-RPC request (or a notification when ID is empty)
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// rpcResult is a successful JSON-RPC response
+type rpcResult struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result"`
+}
+
+// rpcFailure is a failed JSON-RPC response
+type rpcFailure struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *rpcError       `json:"error"`
+}
+
+// rpcError describes why a request failed
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// rpcNotification is an event pushed to subscribed clients
+type rpcNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+// rpcParams are the named parameters of the control methods
+type rpcParams struct {
+	Name       string   `json:"name"`        // process type or instance
+	Path       string   `json:"path"`        // LoadProcfile: absolute Procfile path
+	Port       int      `json:"port"`        // KillPort
+	SinceSeq   uint64   `json:"since_seq"`   // GetLogs
+	Limit      int      `json:"limit"`       // GetLogs
+	Query      string   `json:"query"`       // SearchLogs
+	Regex      bool     `json:"regex"`       // SearchLogs: query is a regular expression
+	Events     []string `json:"events"`      // Subscribe: event names (all when empty)
+	Names      []string `json:"names"`       // Subscribe, SearchLogs: process names (all when empty)
+	Signal     string   `json:"signal"`      // SignalProcess: signal name like HUP
+	LeaderOnly bool     `json:"leader_only"` // SignalProcess: only the process group leader
+	Data       string   `json:"data"`        // WriteStdin
+}
+
+// getControlSocketPath returns the path of the control socket
+func getControlSocketPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, controlSocketName), nil
+}
+
+// controlServer serves the JSON-RPC control API on a Unix socket and
+// forwards supervisor events to subscribed clients
+type controlServer struct {
+	sup      *supervisor.Supervisor
+	listener net.Listener
+	conns    map[*controlConn]bool
+	mu       sync.Mutex
+}
+
+// controlConn is a connected control client
+type controlConn struct {
+	conn   net.Conn
+	out    chan interface{} // messages for the writer goroutine
+	events map[string]bool  // subscribed events, nil until Subscribe
+	names  []string         // subscribed process names (all when empty)
+	done   chan struct{}
+}
+
+// newControlServer creates a control server; it serves once listen is called
+func newControlServer() *controlServer {
+	return &controlServer{conns: make(map[*controlConn]bool)}
+}
+
+// listen starts serving sup on the socket at path. Only the current user
+// may connect. It fails if another session already serves the socket.
+func (c *controlServer) listen(path string, sup *supervisor.Supervisor) error {
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("another Procfile Runner is listening on %s", path)
+	}
+	// Remove a stale socket left by a session that didn't shut down cleanly
+	os.Remove(path)
+
+	// The socket exists with the umask's mode until it is chmodded, so it is
+	// only created in a directory other users can't enter
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		if err := os.Chmod(dir, 0700); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	c.mu.Lock()
+	c.sup = sup
+	c.listener = listener
+	c.mu.Unlock()
+
+	go c.accept(listener)
+	return nil
+}
+
+// close stops serving and disconnects all clients
+func (c *controlServer) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.listener != nil {
+		c.listener.Close()
+		c.listener = nil
+	}
+	for conn := range c.conns {
+		conn.conn.Close()
+	}
+}
+
+// accept handles incoming connections until the listener is closed
+func (c *controlServer) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		client := &controlConn{
+			conn: conn,
+			out:  make(chan interface{}, controlQueueSize),
+			done: make(chan struct{}),
+		}
+		c.mu.Lock()
+		c.conns[client] = true
+		c.mu.Unlock()
+
+		go client.write()
+		go c.serve(client)
+	}
+}
+
+// write sends queued messages to the client, one JSON object per line.
+// After a write error it keeps draining the queue so replies never block.
+func (cc *controlConn) write() {
+	encoder := json.NewEncoder(cc.conn)
+	failed := false
+	for {
+		select {
+		case message := <-cc.out:
+			if failed {
+				continue
+			}
+			if err := encoder.Encode(message); err != nil {
+				failed = true
+				cc.conn.Close()
+			}
+		case <-cc.done:
+			return
+		}
+	}
+}
+
+// serve reads requests from a client until it disconnects
+func (c *controlServer) serve(client *controlConn) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.conns, client)
+		c.mu.Unlock()
+		close(client.done)
+		client.conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(client.conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var req rpcRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			client.reply(nil, nil, &rpcError{Code: rpcParseError, Message: err.Error()})
+			continue
+		}
+		if req.JSONRPC != "2.0" || req.Method == "" {
+			client.reply(req.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: "expected a JSON-RPC 2.0 request"})
+			continue
+		}
+
+		var params rpcParams
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				client.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()})
+				continue
+			}
+		}
+
+		result, err := c.call(client, req.Method, params)
+		if len(req.ID) > 0 {
+			client.reply(req.ID, result, err)
+		}
+	}
+}
+
+// reply queues the response to a request
+func (cc *controlConn) reply(id json.RawMessage, result interface{}, err error) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	if err == nil {
+		cc.out <- rpcResult{JSONRPC: "2.0", ID: id, Result: result}
+		return
+	}
+
+	rpcErr, ok := err.(*rpcError)
+	if !ok {
+		rpcErr = &rpcError{Code: rpcFailed, Message: err.Error()}
+	}
+	cc.out <- rpcFailure{JSONRPC: "2.0", ID: id, Error: rpcErr}
+}
+
+// call runs a control method. Method names match the desktop app bindings.
+func (c *controlServer) call(client *controlConn, method string, params rpcParams) (interface{}, error) {
+	c.mu.Lock()
+	sup := c.sup
+	c.mu.Unlock()
+
+	requireName := func() error {
+		if params.Name == "" {
+			return &rpcError{Code: rpcInvalidParams, Message: "missing name"}
+		}
+		return nil
+	}
+
+	switch method {
+	case "GetStatus":
+		return sup.Status(), nil
+	case "StartProcess":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		return true, sup.Start(params.Name)
+	case "StopProcess":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		return true, sup.Stop(params.Name)
+	case "RestartProcess":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		return true, sup.Restart(params.Name)
+	case "SignalProcess":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		if params.Signal == "" {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "missing signal"}
+		}
+		return true, sup.Signal(params.Name, params.Signal, params.LeaderOnly)
+	case "WriteStdin":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		return true, sup.WriteStdin(params.Name, params.Data)
+	case "CloseStdin":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		return true, sup.CloseStdin(params.Name)
+	case "StartAllProcesses":
+		return true, sup.StartAll()
+	case "StopAllProcesses":
+		return true, sup.StopAll()
+	case "LoadProcfile":
+		if !filepath.IsAbs(params.Path) {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "path must be absolute"}
+		}
+		return true, sup.Load(params.Path)
+	case "GetLogs":
+		return sup.Logs(params.Name, params.SinceSeq, params.Limit), nil
+	case "SearchLogs":
+		return sup.SearchLogs(params.Query, params.Regex, params.Names)
+	case "GetEffectiveEnv":
+		if err := requireName(); err != nil {
+			return nil, err
+		}
+		return sup.EffectiveEnv(params.Name)
+	case "GetProcfileContent":
+		return procfileContent(sup)
+	case "GetActivePorts":
+		return activePorts(), nil
+	case "KillPort":
+		if params.Port <= 0 {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: "missing port"}
+		}
+		return true, killPort(params.Port)
+	case "Subscribe":
+		events := make(map[string]bool)
+		for _, event := range params.Events {
+			events[event] = true
+		}
+		if len(events) == 0 {
+			events = map[string]bool{"process-status": true, "process-output": true, "procfile-loaded": true, "process-scaled": true, "procfile-reloaded": true}
+		}
+		c.mu.Lock()
+		client.events = events
+		client.names = params.Names
+		c.mu.Unlock()
+		return true, nil
+	case "Unsubscribe":
+		c.mu.Lock()
+		client.events = nil
+		c.mu.Unlock()
+		return true, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", method)}
+}
+
+// broadcast sends an event to the clients subscribed to it. Clients that
+// can't keep up miss events rather than blocking the processes.
+func (c *controlServer) broadcast(event string, name string, data interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for client := range c.conns {
+		if !client.events[event] {
+			continue
+		}
+		if name != "" && len(client.names) > 0 && !subscribedTo(name, client.names) {
+			continue
+		}
+		select {
+		case client.out <- rpcNotification{JSONRPC: "2.0", Method: event, Params: data}:
+		default:
+		}
+	}
+}
+
+// subscribedTo reports whether an instance name matches one of the
+// subscribed names; a process type matches all its instances
+func subscribedTo(name string, names []string) bool {
+	for _, n := range names {
+		if name == n || (len(name) > len(n) && name[:len(n)+1] == n+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// OnStatus forwards process-status to subscribers
+func (c *controlServer) OnStatus(status supervisor.ProcessStatus) {
+	c.broadcast("process-status", status.Name, status)
+}
+
+// OnOutput forwards process-output to subscribers
+func (c *controlServer) OnOutput(output supervisor.ProcessOutput) {
+	c.broadcast("process-output", output.Name, output)
+}
+
+// OnProcfileLoaded forwards procfile-loaded to subscribers
+func (c *controlServer) OnProcfileLoaded(loaded supervisor.ProcfileLoaded) {
+	c.broadcast("procfile-loaded", "", loaded)
+}
+
+// OnScaled forwards process-scaled to subscribers
+func (c *controlServer) OnScaled(scaled supervisor.ProcessScaled) {
+	c.broadcast("process-scaled", "", scaled)
+}
+
+// OnProcfileReloaded forwards procfile-reloaded to subscribers
+func (c *controlServer) OnProcfileReloaded(reloaded supervisor.ProcfileReloaded) {
+	c.broadcast("procfile-reloaded", "", reloaded)
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"procfile-runner/supervisor"
+)
+
+// ctlTailLines is how many buffered lines `ctl tail` prints before following
+const ctlTailLines = 20
+
+const ctlUsage = `Usage: procfile-runner ctl <command> [args]
+
+Commands:
+  status              list processes with their status and pid
+  start [name]        start a process, or all processes
+  stop [name]         stop a process, or all processes
+  restart <name>      restart a process
+  load <Procfile>     load another Procfile
+  ports               list processes listening on ports 3000-9000
+  kill-port <port>    kill the process listening on a port
+  tail [name...]      print recent output and follow it (Ctrl-C to quit)
+  env <name>          print the environment of a process and where each value comes from
+`
+
+// ctlClient is a JSON-RPC client for the control socket
+type ctlClient struct {
+	conn     net.Conn
+	scanner  *bufio.Scanner
+	encoder  *json.Encoder
+	nextID   int
+	onNotify func(method string, params json.RawMessage) // receives pushed events
+}
+
+// rpcMessage is any message read from the control socket
+type rpcMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// dialControl connects to the control socket at path
+func dialControl(path string) (*ctlClient, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("no running Procfile Runner found (%s)", path)
+	}
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &ctlClient{conn: conn, scanner: scanner, encoder: json.NewEncoder(conn)}, nil
+}
+
+// close disconnects from the control socket
+func (c *ctlClient) close() {
+	c.conn.Close()
+}
+
+// call runs a control method and decodes its result into result (if not
+// nil). Events that arrive meanwhile go to onNotify.
+func (c *ctlClient) call(method string, params interface{}, result interface{}) error {
+	c.nextID++
+	id := c.nextID
+	req := map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method}
+	if params != nil {
+		req["params"] = params
+	}
+	if err := c.encoder.Encode(req); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := c.read()
+		if err != nil {
+			return err
+		}
+		if msg.ID == nil || *msg.ID != id {
+			continue
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	}
+}
+
+// follow passes pushed events to onNotify until the connection closes
+func (c *ctlClient) follow() error {
+	for {
+		if _, err := c.read(); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads the next message, handing notifications to onNotify
+func (c *ctlClient) read() (*rpcMessage, error) {
+	if !c.scanner.Scan() {
+		if err := c.scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	var msg rpcMessage
+	if err := json.Unmarshal(c.scanner.Bytes(), &msg); err != nil {
+		return nil, err
+	}
+	if msg.ID == nil && msg.Method != "" && c.onNotify != nil {
+		c.onNotify(msg.Method, msg.Params)
+	}
+	return &msg, nil
+}
+
+// runCtl runs a `ctl` subcommand against a running session and returns the
+// process exit code
+func runCtl(args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Print(ctlUsage)
+		if len(args) == 0 {
+			return exitUsage
+		}
+		return exitOK
+	}
+
+	path, err := getControlSocketPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailed
+	}
+	client, err := dialControl(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ctl: %v\n", err)
+		return exitFailed
+	}
+	defer client.close()
+
+	if err := ctlCommand(client, os.Stdout, args[0], args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "ctl %s: %v\n", args[0], err)
+		var usage ctlUsageError
+		if errors.As(err, &usage) {
+			fmt.Fprint(os.Stderr, "\n"+ctlUsage)
+			return exitUsage
+		}
+		return exitFailed
+	}
+	return exitOK
+}
+
+// ctlUsageError reports bad ctl arguments
+type ctlUsageError string
+
+func (e ctlUsageError) Error() string {
+	return string(e)
+}
+
+// ctlCommand runs one ctl command, writing its output to out
+func ctlCommand(client *ctlClient, out io.Writer, command string, args []string) error {
+	name := func() (map[string]string, error) {
+		if len(args) != 1 {
+			return nil, ctlUsageError("expected one process name")
+		}
+		return map[string]string{"name": args[0]}, nil
+	}
+
+	switch command {
+	case "status":
+		var statuses []supervisor.ProcessStatus
+		if err := client.call("GetStatus", nil, &statuses); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSTATUS\tPID\tRESTARTS")
+		for _, status := range statuses {
+			pid := "-"
+			if status.PID > 0 && status.Status != "stopped" && status.Status != "crashed" {
+				pid = strconv.Itoa(status.PID)
+			}
+			state := status.Status
+			if status.ExitCode != nil {
+				state = fmt.Sprintf("%s (exit %d)", state, *status.ExitCode)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", status.Name, state, pid, status.Restarts)
+		}
+		return w.Flush()
+
+	case "start", "stop":
+		if len(args) == 0 {
+			method := map[string]string{"start": "StartAllProcesses", "stop": "StopAllProcesses"}[command]
+			return client.call(method, nil, nil)
+		}
+		params, err := name()
+		if err != nil {
+			return err
+		}
+		method := map[string]string{"start": "StartProcess", "stop": "StopProcess"}[command]
+		return client.call(method, params, nil)
+
+	case "restart":
+		params, err := name()
+		if err != nil {
+			return err
+		}
+		return client.call("RestartProcess", params, nil)
+
+	case "load":
+		if len(args) != 1 {
+			return ctlUsageError("expected a Procfile path")
+		}
+		path, err := filepath.Abs(args[0])
+		if err != nil {
+			return err
+		}
+		return client.call("LoadProcfile", map[string]string{"path": path}, nil)
+
+	case "ports":
+		var ports []PortInfo
+		if err := client.call("GetActivePorts", nil, &ports); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "PORT\tPID\tPROCESS\tCOMMAND")
+		for _, port := range ports {
+			fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", port.Port, port.PID, port.Process, port.Command)
+		}
+		return w.Flush()
+
+	case "kill-port":
+		if len(args) != 1 {
+			return ctlUsageError("expected a port")
+		}
+		port, err := strconv.Atoi(args[0])
+		if err != nil {
+			return ctlUsageError(fmt.Sprintf("invalid port %q", args[0]))
+		}
+		return client.call("KillPort", map[string]int{"port": port}, nil)
+
+	case "env":
+		params, err := name()
+		if err != nil {
+			return err
+		}
+		var vars []supervisor.EnvVar
+		if err := client.call("GetEffectiveEnv", params, &vars); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+		for _, v := range vars {
+			source := v.Source
+			if len(v.Overrides) > 0 {
+				source += " (overrides " + strings.Join(v.Overrides, ", ") + ")"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v.Key, v.Value, source)
+		}
+		return w.Flush()
+
+	case "tail":
+		return ctlTail(client, out, args)
+	}
+	return ctlUsageError(fmt.Sprintf("unknown command %q", command))
+}
+
+// ctlTail prints the recent output of the given processes (all when none
+// are given) and then follows their output and status changes
+func ctlTail(client *ctlClient, out io.Writer, names []string) error {
+	var statuses []supervisor.ProcessStatus
+	if err := client.call("GetStatus", nil, &statuses); err != nil {
+		return err
+	}
+	var instances []string
+	for _, status := range statuses {
+		instances = append(instances, status.Name)
+	}
+	printer := newCLIPrinter(out, out == os.Stdout && isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "")
+	printer.setNames(instances)
+
+	// Subscribe before reading the history so nothing is lost in between;
+	// output that arrives meanwhile waits until the history is printed
+	var pending []supervisor.ProcessOutput
+	following := false
+	client.onNotify = func(method string, params json.RawMessage) {
+		switch method {
+		case "process-output":
+			var output supervisor.ProcessOutput
+			if json.Unmarshal(params, &output) != nil {
+				return
+			}
+			if following {
+				printer.OnOutput(output)
+			} else {
+				pending = append(pending, output)
+			}
+		case "process-status":
+			var status supervisor.ProcessStatus
+			if json.Unmarshal(params, &status) == nil {
+				printer.OnStatus(status)
+			}
+		}
+	}
+	subscription := map[string]interface{}{"events": []string{"process-output", "process-status"}, "names": names}
+	if err := client.call("Subscribe", subscription, nil); err != nil {
+		return err
+	}
+
+	queries := names
+	if len(queries) == 0 {
+		queries = []string{""}
+	}
+	var history []supervisor.ProcessOutput
+	for _, name := range queries {
+		var lines []supervisor.ProcessOutput
+		if err := client.call("GetLogs", map[string]interface{}{"name": name, "limit": ctlTailLines}, &lines); err != nil {
+			return err
+		}
+		history = append(history, lines...)
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Seq < history[j].Seq })
+
+	var lastSeq uint64
+	for _, line := range history {
+		printer.OnOutput(line)
+		lastSeq = line.Seq
+	}
+	for _, line := range pending {
+		if line.Seq > lastSeq {
+			printer.OnOutput(line)
+		}
+	}
+	following = true
+
+	if err := client.follow(); err != io.EOF {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"procfile-runner/supervisor"
+)
+
+// composeRestart maps restart policies to compose's restart
+var composeRestart = map[string]string{
+	supervisor.RestartNever:     `"no"`,
+	supervisor.RestartOnFailure: "on-failure",
+	supervisor.RestartAlways:    "always",
+}
+
+// compose renders a docker-compose skeleton with a service per process
+// type; scaled types become replicas. Every service builds the project
+// directory, so it needs a Dockerfile there or an image instead. Ports are
+// published for processes whose command uses PORT or that have a tcp/http
+// readiness check.
+func compose(app string, plan supervisor.Plan) []File {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Generated by procfile-runner from %s\n", plan.Path)
+	sb.WriteString("# A skeleton: review build, ports and volumes before deploying.\n")
+	fmt.Fprintf(&sb, "name: %s\n", app)
+	sb.WriteString("services:\n")
+
+	for _, process := range plan.Processes {
+		fmt.Fprintf(&sb, "  %s:\n", unsafeNameChars.ReplaceAllString(process.Name, "-"))
+		fmt.Fprintf(&sb, "    build: %s\n", yamlString(plan.Dir))
+		fmt.Fprintf(&sb, "    command: [%s, \"-c\", %s]\n", yamlString(shell), yamlString(composeEscape(process.Command)))
+
+		sb.WriteString("    environment:\n")
+		for _, pair := range instanceEnv(plan, process, 1) {
+			value := pair[1]
+			if pair[0] == "PS" && process.Count > 1 {
+				// Replicas share one definition
+				value = process.Name
+			}
+			fmt.Fprintf(&sb, "      %s: %s\n", pair[0], yamlString(composeEscape(value)))
+		}
+
+		if process.Count == 1 && publishesPort(process) {
+			sb.WriteString("    ports:\n")
+			fmt.Fprintf(&sb, "      - \"%d:%d\"\n", process.BasePort, process.BasePort)
+		}
+		if process.Count > 1 {
+			sb.WriteString("    deploy:\n")
+			fmt.Fprintf(&sb, "      replicas: %d\n", process.Count)
+		}
+		if len(process.DependsOn) > 0 {
+			sb.WriteString("    depends_on:\n")
+			for _, dep := range process.DependsOn {
+				fmt.Fprintf(&sb, "      - %s\n", unsafeNameChars.ReplaceAllString(dep, "-"))
+			}
+		}
+		fmt.Fprintf(&sb, "    restart: %s\n", composeRestart[process.Restart])
+		fmt.Fprintf(&sb, "    stop_signal: %s\n", process.StopSignal)
+		fmt.Fprintf(&sb, "    stop_grace_period: %ss\n", seconds(process.StopTimeout))
+	}
+
+	return []File{{Name: "docker-compose.yml", Content: sb.String()}}
+}
+
+// publishesPort reports whether a process looks like it listens on its PORT
+func publishesPort(process supervisor.PlannedProcess) bool {
+	return process.Ready != nil && (process.Ready.TCP != "" || process.Ready.HTTP != "") ||
+		strings.Contains(process.Command, "PORT")
+}
+
+// composeEscape escapes compose's variable interpolation ($)
+func composeEscape(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// yamlString quotes a YAML scalar; JSON strings are valid YAML
+func yamlString(value string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
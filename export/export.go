@@ -0,0 +1,114 @@
+// Package export turns a loaded Procfile into the configuration of another
+// process manager, like `foreman export`
+package export
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"procfile-runner/supervisor"
+)
+
+// Formats are the supported export formats
+var Formats = []string{"systemd", "supervisord", "compose"}
+
+// File is a generated config file
+type File struct {
+	Name    string `json:"name"` // relative to the export directory
+	Content string `json:"content"`
+}
+
+// shell runs the Procfile commands, as the supervisor does
+const shell = "/bin/sh"
+
+// Generate renders plan in format. app prefixes unit and program names.
+func Generate(format string, app string, plan supervisor.Plan) ([]File, error) {
+	if len(plan.Processes) == 0 {
+		return nil, fmt.Errorf("no processes to export")
+	}
+	if app == "" {
+		app = AppName(plan)
+	}
+
+	switch format {
+	case "systemd":
+		return systemd(app, plan), nil
+	case "supervisord":
+		return supervisord(app, plan), nil
+	case "compose", "docker-compose":
+		return compose(app, plan), nil
+	}
+	return nil, fmt.Errorf("unknown export format %q (expected %s)", format, strings.Join(Formats, ", "))
+}
+
+// Write writes files into dir, creating it if needed, and returns their paths
+func Write(dir string, files []File) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if err := os.WriteFile(path, []byte(file.Content), 0644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// unsafeNameChars are replaced in unit, program and service names
+var unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// AppName derives an app name from the directory of the Procfile
+func AppName(plan supervisor.Plan) string {
+	name := strings.Trim(unsafeNameChars.ReplaceAllString(strings.ToLower(filepath.Base(plan.Dir)), "-"), "-.")
+	if name == "" {
+		return "app"
+	}
+	return name
+}
+
+// unitName returns the name of a process instance in the exported config
+func unitName(app string, instance string) string {
+	return app + "-" + unsafeNameChars.ReplaceAllString(instance, "-")
+}
+
+// instanceEnv returns the environment of the nth instance (1-based) as
+// sorted KEY=value pairs: the env file variables, the process's own and
+// PORT and PS like the supervisor sets them
+func instanceEnv(plan supervisor.Plan, process supervisor.PlannedProcess, n int) [][2]string {
+	env := make(map[string]string, len(plan.Env)+len(process.Env)+2)
+	for key, value := range plan.Env {
+		env[key] = value
+	}
+	for key, value := range process.Env {
+		env[key] = value
+	}
+	env["PORT"] = strconv.Itoa(process.BasePort + n - 1)
+	env["PS"] = fmt.Sprintf("%s.%d", process.Name, n)
+
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([][2]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, [2]string{key, env[key]})
+	}
+	return pairs
+}
+
+// seconds formats a duration as whole seconds
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(d.Seconds()))
+}
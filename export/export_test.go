@@ -0,0 +1,164 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"procfile-runner/supervisor"
+)
+
+// loadPlan loads a Procfile with its side config and .env from a temp dir
+func loadPlan(t *testing.T, procfile string, options string, env string) supervisor.Plan {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "My App")
+	os.Mkdir(dir, 0755)
+	path := filepath.Join(dir, "Procfile")
+	os.WriteFile(path, []byte(procfile), 0644)
+	if options != "" {
+		os.WriteFile(path+".json", []byte(options), 0644)
+	}
+	if env != "" {
+		os.WriteFile(filepath.Join(dir, ".env"), []byte(env), 0644)
+	}
+
+	sup := supervisor.New(supervisor.MultiSink{})
+	if err := sup.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	return sup.Plan()
+}
+
+func TestPlan(t *testing.T) {
+	plan := loadPlan(t,
+		"web: serve -p $PORT\nworker: work\n# old: legacy\n",
+		`{"processes": {"web": {"depends_on": ["worker"], "restart": "always", "stop_signal": "INT", "stop_timeout": 10}}, "formation": "worker=2"}`,
+		"SECRET=s3cr3t\n")
+
+	if AppName(plan) != "my-app" {
+		t.Errorf("Expected app name my-app, got %q", AppName(plan))
+	}
+	if len(plan.Processes) != 2 || plan.Processes[0].Name != "worker" || plan.Processes[1].Name != "web" {
+		t.Fatalf("Expected worker then web without the disabled process, got %+v", plan.Processes)
+	}
+	worker, web := plan.Processes[0], plan.Processes[1]
+	if worker.Count != 2 || worker.BasePort != 5100 || worker.Restart != supervisor.RestartOnFailure || worker.StopSignal != "SIGTERM" {
+		t.Errorf("Unexpected worker defaults: %+v", worker)
+	}
+	if web.Restart != "always" || web.StopSignal != "SIGINT" || web.StopTimeout.Seconds() != 10 {
+		t.Errorf("Expected web options to be resolved, got %+v", web)
+	}
+	if plan.Env["SECRET"] != "s3cr3t" {
+		t.Errorf("Expected .env variables in the plan, got %v", plan.Env)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	plan := loadPlan(t,
+		"web: serve -p $PORT --name \"50% off\"\nworker: work\n",
+		`{"processes": {"web": {"depends_on": ["worker"], "restart": "never", "ready": {"tcp": "5000"}}}, "formation": "worker=2"}`,
+		"")
+
+	files, err := Generate("systemd", "shop", plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	contents := make(map[string]string)
+	for _, file := range files {
+		names = append(names, file.Name)
+		contents[file.Name] = file.Content
+	}
+	if strings.Join(names, " ") != "shop-worker.1.service shop-worker.2.service shop-web.service shop.target" {
+		t.Errorf("Unexpected systemd files: %v", names)
+	}
+	web := contents["shop-web.service"]
+	for _, want := range []string{
+		`ExecStart=/bin/sh -c "serve -p $$PORT --name \"50%% off\""`,
+		"After=shop-worker.1.service shop-worker.2.service",
+		`Environment="PORT=5000"`,
+		"Restart=no",
+		"WantedBy=shop.target",
+	} {
+		if !strings.Contains(web, want) {
+			t.Errorf("Expected web unit to contain %q:\n%s", want, web)
+		}
+	}
+	if !strings.Contains(contents["shop-worker.2.service"], `Environment="PORT=5101"`) {
+		t.Errorf("Expected the second worker on PORT 5101")
+	}
+	if !strings.Contains(contents["shop.target"], "Wants=shop-worker.1.service shop-worker.2.service shop-web.service") {
+		t.Errorf("Expected the target to want every unit:\n%s", contents["shop.target"])
+	}
+
+	files, err = Generate("supervisord", "shop", plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"programs=shop-worker.1,shop-worker.2,shop-web",
+		`command=/bin/sh -c "serve -p $PORT --name \"50%% off\""`,
+		`environment=PORT="5000",PS="web.1"`,
+		"autorestart=false",
+		"autorestart=unexpected",
+	} {
+		if !strings.Contains(files[0].Content, want) {
+			t.Errorf("Expected supervisord config to contain %q:\n%s", want, files[0].Content)
+		}
+	}
+
+	files, err = Generate("compose", "shop", plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"name: shop",
+		`command: ["/bin/sh", "-c", "serve -p $$PORT --name \"50% off\""]`,
+		"replicas: 2",
+		`- "5000:5000"`,
+		"depends_on:\n      - worker",
+		`restart: "no"`,
+	} {
+		if !strings.Contains(files[0].Content, want) {
+			t.Errorf("Expected compose file to contain %q:\n%s", want, files[0].Content)
+		}
+	}
+
+	if _, err := Generate("kubernetes", "shop", plan); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+
+	dir := filepath.Join(t.TempDir(), "out")
+	paths, err := Write(dir, files)
+	if err != nil || len(paths) != 1 {
+		t.Fatalf("Expected one written file, got %v (%v)", paths, err)
+	}
+	if data, _ := os.ReadFile(paths[0]); string(data) != files[0].Content {
+		t.Error("Expected the written file to match")
+	}
+}
+
+func TestGenerateMultilineEnv(t *testing.T) {
+	plan := loadPlan(t, "web: serve\n", "", "TLS_CERT=\"line one\nline two\"\n")
+	if plan.Env["TLS_CERT"] != "line one\nline two" {
+		t.Fatalf("Expected a multiline value, got %q", plan.Env["TLS_CERT"])
+	}
+
+	for format, want := range map[string]string{
+		"systemd":     `Environment="TLS_CERT=line one\nline two"`,
+		"supervisord": `TLS_CERT="line one\nline two"`,
+	} {
+		files, err := Generate(format, "shop", plan)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := files[0].Content
+		if !strings.Contains(content, want) {
+			t.Errorf("Expected %s output to contain %q:\n%s", format, want, content)
+		}
+		if strings.Contains(content, "line one\n") {
+			t.Errorf("Expected no raw line break in the %s value:\n%s", format, content)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"procfile-runner/supervisor"
+)
+
+// supervisordRestart maps restart policies to supervisord's autorestart
+var supervisordRestart = map[string]string{
+	supervisor.RestartNever:     "false",
+	supervisor.RestartOnFailure: "unexpected",
+	supervisor.RestartAlways:    "true",
+}
+
+// supervisord renders one config with a program per process instance,
+// grouped under the app name. Dependencies only order the start through
+// priority; supervisord doesn't wait for them to be ready.
+func supervisord(app string, plan supervisor.Plan) []File {
+	var programs []string
+	var body strings.Builder
+
+	for i, process := range plan.Processes {
+		for n, instance := range process.Instances() {
+			program := unitName(app, instance)
+			programs = append(programs, program)
+
+			var env []string
+			for _, pair := range instanceEnv(plan, process, n+1) {
+				env = append(env, pair[0]+"="+supervisordQuote(pair[1]))
+			}
+
+			fmt.Fprintf(&body, "\n[program:%s]\n", program)
+			fmt.Fprintf(&body, "command=%s -c %s\n", shell, supervisordQuote(process.Command))
+			fmt.Fprintf(&body, "directory=%s\n", supervisordEscape(plan.Dir))
+			fmt.Fprintf(&body, "environment=%s\n", strings.Join(env, ","))
+			body.WriteString("autostart=true\n")
+			fmt.Fprintf(&body, "autorestart=%s\n", supervisordRestart[process.Restart])
+			fmt.Fprintf(&body, "startretries=%d\n", process.MaxRestarts)
+			// Start order follows depends_on: lower priorities start first
+			fmt.Fprintf(&body, "priority=%d\n", 100+i*10)
+			fmt.Fprintf(&body, "stopsignal=%s\n", strings.TrimPrefix(process.StopSignal, "SIG"))
+			fmt.Fprintf(&body, "stopwaitsecs=%s\n", seconds(process.StopTimeout))
+			body.WriteString("stopasgroup=true\n")
+			body.WriteString("killasgroup=true\n")
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "; Generated by procfile-runner from %s\n", plan.Path)
+	fmt.Fprintf(&sb, "[group:%s]\n", app)
+	fmt.Fprintf(&sb, "programs=%s\n", strings.Join(programs, ","))
+	sb.WriteString(body.String())
+
+	return []File{{Name: app + ".conf", Content: sb.String()}}
+}
+
+// supervisordEscape escapes Python string expansion (%) in a value
+func supervisordEscape(value string) string {
+	return strings.ReplaceAll(value, "%", "%%")
+}
+
+// supervisordQuote quotes a value for supervisord's shell-like parsing of
+// command and environment. Line breaks are written as \n and \r, since a
+// raw one would end the setting.
+func supervisordQuote(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(value)
+	return `"` + supervisordEscape(value) + `"`
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+
+	"procfile-runner/supervisor"
+)
+
+// systemdRestart maps restart policies to systemd's Restart=
+var systemdRestart = map[string]string{
+	supervisor.RestartNever:     "no",
+	supervisor.RestartOnFailure: "on-failure",
+	supervisor.RestartAlways:    "always",
+}
+
+// systemd renders user units: one service per process instance and a target
+// that starts them all. Install them into ~/.config/systemd/user.
+func systemd(app string, plan supervisor.Plan) []File {
+	var files []File
+	var units []string
+
+	// Units of each process type, for depends_on
+	typeUnits := make(map[string][]string)
+	for _, process := range plan.Processes {
+		for _, instance := range process.Instances() {
+			typeUnits[process.Name] = append(typeUnits[process.Name], unitName(app, instance)+".service")
+		}
+	}
+
+	for _, process := range plan.Processes {
+		var deps []string
+		for _, dep := range process.DependsOn {
+			deps = append(deps, typeUnits[dep]...)
+		}
+
+		for i, instance := range process.Instances() {
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "# Generated by procfile-runner from %s\n", plan.Path)
+			sb.WriteString("[Unit]\n")
+			fmt.Fprintf(&sb, "Description=%s %s\n", app, instance)
+			fmt.Fprintf(&sb, "PartOf=%s.target\n", app)
+			if len(deps) > 0 {
+				fmt.Fprintf(&sb, "Wants=%s\n", strings.Join(deps, " "))
+				fmt.Fprintf(&sb, "After=%s\n", strings.Join(deps, " "))
+			}
+			fmt.Fprintf(&sb, "StartLimitIntervalSec=%s\n", seconds(process.RestartWindow))
+			fmt.Fprintf(&sb, "StartLimitBurst=%d\n", process.MaxRestarts+1)
+
+			sb.WriteString("\n[Service]\n")
+			sb.WriteString("Type=simple\n")
+			fmt.Fprintf(&sb, "WorkingDirectory=%s\n", strings.ReplaceAll(plan.Dir, "%", "%%"))
+			for _, pair := range instanceEnv(plan, process, i+1) {
+				fmt.Fprintf(&sb, "Environment=%s\n", systemdQuote(pair[0]+"="+pair[1], false))
+			}
+			fmt.Fprintf(&sb, "ExecStart=%s -c %s\n", shell, systemdQuote(process.Command, true))
+			fmt.Fprintf(&sb, "Restart=%s\n", systemdRestart[process.Restart])
+			fmt.Fprintf(&sb, "RestartSec=%s\n", seconds(process.RestartDelay))
+			fmt.Fprintf(&sb, "KillSignal=%s\n", process.StopSignal)
+			fmt.Fprintf(&sb, "TimeoutStopSec=%s\n", seconds(process.StopTimeout))
+
+			sb.WriteString("\n[Install]\n")
+			fmt.Fprintf(&sb, "WantedBy=%s.target\n", app)
+
+			unit := unitName(app, instance) + ".service"
+			units = append(units, unit)
+			files = append(files, File{Name: unit, Content: sb.String()})
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Generated by procfile-runner from %s\n", plan.Path)
+	sb.WriteString("[Unit]\n")
+	fmt.Fprintf(&sb, "Description=%s\n", app)
+	fmt.Fprintf(&sb, "Wants=%s\n", strings.Join(units, " "))
+	sb.WriteString("\n[Install]\n")
+	sb.WriteString("WantedBy=default.target\n")
+	files = append(files, File{Name: app + ".target", Content: sb.String()})
+
+	return files
+}
+
+// systemdQuote quotes a unit file value. Specifiers (%) and line breaks are
+// escaped, and for command lines variable references ($) too.
+func systemdQuote(value string, command bool) string {
+	value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "%", "%%", "\n", `\n`, "\r", `\r`).Replace(value)
+	if command {
+		value = strings.ReplaceAll(value, "$", "$$")
+	}
+	return `"` + value + `"`
+}
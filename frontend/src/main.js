@@ -9,6 +9,7 @@ import {
   StartAllProcesses,
   StopAllProcesses,
   SetGlobalAutoRestart,
+  SetAutoReload,
   GetRecentProjects,
   AddRecentProject,
   SaveLog,
@@ -24,7 +25,19 @@ import {
   EnableProcess,
   GetProcfileContent,
   SaveProcfileContent,
-  GetDemoProcfilePath
+  GetDemoProcfilePath,
+  ScaleProcess,
+  SetTerminalSize,
+  WriteStdin,
+  CloseStdin,
+  GetLogs,
+  ClearLogs,
+  ListLogFiles,
+  OpenLogFile,
+  ExportProcfile,
+  OpenDirectoryDialog,
+  ScanProject,
+  ImportProcfile
 } from '../wailsjs/go/main/App';
 
 // Process colors for visual distinction - vibrant and well-separated hues
@@ -209,6 +222,7 @@ const state = {
   logs: [],
   activeTab: "all",
   procfilePath: null,
+  importDir: null, // project directory the Procfile modal creates a Procfile for
   hiddenProcesses: new Set(),
   searchQuery: "",
   showTimestamps: false,
@@ -222,17 +236,26 @@ const state = {
 const elements = {
   procfilePath: document.getElementById("procfile-path"),
   btnOpen: document.getElementById("btn-open"),
+  btnOpenFolder: document.getElementById("btn-open-folder"),
   btnStartAll: document.getElementById("btn-start-all"),
+  exportFormat: document.getElementById("export-format"),
   btnStopAll: document.getElementById("btn-stop-all"),
   btnClearLog: document.getElementById("btn-clear-log"),
   btnCopyPath: document.getElementById("btn-copy-path"),
   btnSaveLog: document.getElementById("btn-save-log"),
+  btnLogFiles: document.getElementById("btn-log-files"),
+  logFilesModal: document.getElementById("log-files-modal"),
+  logFilesList: document.getElementById("log-files-list"),
+  logFilesClose: document.getElementById("log-files-close"),
+  logFilesBackdrop: document.getElementById("log-files-backdrop"),
   processList: document.getElementById("process-list"),
   logTabs: document.getElementById("log-tabs"),
   logOutput: document.getElementById("log-output"),
   statusText: document.getElementById("status-text"),
   processCount: document.getElementById("process-count"),
   autoRestartToggle: document.getElementById("auto-restart-toggle"),
+  autoReloadToggle: document.getElementById("auto-reload-toggle"),
+  reloadRestartToggle: document.getElementById("reload-restart-toggle"),
   recentProjects: document.getElementById("recent-projects"),
   recentProjectsList: document.getElementById("recent-projects-list"),
   logSearch: document.getElementById("log-search"),
@@ -240,6 +263,9 @@ const elements = {
   timestampToggle: document.getElementById("timestamp-toggle"),
   portsList: document.getElementById("ports-list"),
   btnRefreshPorts: document.getElementById("btn-refresh-ports"),
+  stdinBar: document.getElementById("stdin-bar"),
+  stdinInput: document.getElementById("stdin-input"),
+  btnSendEof: document.getElementById("btn-send-eof"),
   askOpencodeBar: document.getElementById("ask-opencode-bar"),
   askOpencodeInput: document.getElementById("ask-opencode-input"),
   btnAskOpencode: document.getElementById("btn-ask-opencode"),
@@ -257,6 +283,7 @@ const elements = {
   procfileModalCancel: document.getElementById("procfile-modal-cancel"),
   procfileModalSave: document.getElementById("procfile-modal-save"),
   procfileContent: document.getElementById("procfile-content"),
+  procfileModalTitle: document.getElementById("procfile-modal-title"),
 };
 
 // Initialize app
@@ -267,18 +294,26 @@ async function init() {
   loadRecentProjects();
   await loadSettings();
   await checkOpenCodeInstalled();
+  syncTerminalSize();
   console.log("App initialized");
 }
 
 // Setup DOM event listeners
 function setupEventListeners() {
   elements.btnOpen.addEventListener("click", openProcfile);
+  elements.btnOpenFolder.addEventListener("click", openFolder);
   elements.btnStartAll.addEventListener("click", startAllProcesses);
   elements.btnStopAll.addEventListener("click", stopAllProcesses);
+  elements.exportFormat.addEventListener("change", exportProcfile);
   elements.btnClearLog.addEventListener("click", clearLogs);
   elements.btnSaveLog.addEventListener("click", saveCurrentLog);
   elements.btnCopyPath.addEventListener("click", copyLogPath);
+  elements.btnLogFiles.addEventListener("click", openLogFilesModal);
+  elements.logFilesClose.addEventListener("click", closeLogFilesModal);
+  elements.logFilesBackdrop.addEventListener("click", closeLogFilesModal);
   elements.autoRestartToggle.addEventListener("change", toggleAutoRestart);
+  elements.autoReloadToggle.addEventListener("change", toggleAutoReload);
+  elements.reloadRestartToggle.addEventListener("change", toggleAutoReload);
 
   // Author link
   document.getElementById("author-link").addEventListener("click", () => {
@@ -301,6 +336,10 @@ function setupEventListeners() {
   // Refresh ports button
   elements.btnRefreshPorts.addEventListener("click", refreshPorts);
 
+  // Process stdin
+  elements.stdinInput.addEventListener("keydown", handleStdinKeydown);
+  elements.btnSendEof.addEventListener("click", sendStdinEOF);
+
   // Ask OpenCode
   elements.btnAskOpencode.addEventListener("click", askOpenCode);
   elements.btnCopyOpencodeQ.addEventListener("click", copyOpenCodeQuestion);
@@ -319,22 +358,52 @@ function setupEventListeners() {
   elements.procfileModalCancel.addEventListener("click", closeProcfileModal);
   elements.procfileModalSave.addEventListener("click", saveProcfileContent);
 
+  // Keep the size of PTY processes in sync with the log pane
+  let resizeTimer = null;
+  window.addEventListener("resize", () => {
+    clearTimeout(resizeTimer);
+    resizeTimer = setTimeout(syncTerminalSize, 200);
+  });
+
   // Setup keyboard shortcuts
   setupKeyboardShortcuts();
 }
 
+// Tell the backend how many columns/rows fit in the log pane (used by PTY processes)
+function syncTerminalSize() {
+  const container = elements.logOutput.parentElement;
+  const probe = document.createElement("span");
+  probe.className = "font-mono text-sm invisible absolute";
+  probe.textContent = "M".repeat(10);
+  container.appendChild(probe);
+  const charWidth = probe.getBoundingClientRect().width / 10;
+  const lineHeight = probe.getBoundingClientRect().height;
+  probe.remove();
+
+  if (!charWidth || !lineHeight) return;
+  const cols = Math.floor(container.clientWidth / charWidth);
+  const rows = Math.floor(container.clientHeight / lineHeight);
+  SetTerminalSize(cols, rows).catch((err) => console.error("Failed to set terminal size:", err));
+}
+
 // Setup Wails event listeners
 function setupWailsListeners() {
   EventsOn("process-output", (data) => {
     console.log("process-output event:", data);
-    const { name, line, is_stderr } = data;
-    addLogLine(name, line, is_stderr);
+    const { name, line, is_stderr, time } = data;
+    addLogLine(name, line, is_stderr, time);
   });
 
   EventsOn("process-status", (data) => {
     console.log("process-status event:", data);
-    const { name, status, exit_code } = data;
-    updateProcessStatus(name, status, exit_code);
+    const { name, status, exit_code, restarts } = data;
+    updateProcessStatus(name, status, exit_code, restarts);
+  });
+
+  EventsOn("process-scaled", (data) => {
+    console.log("process-scaled event:", data);
+    const { type, instances } = data;
+    handleProcessScaled(type, instances);
   });
 
   EventsOn("procfile-loaded", (data) => {
@@ -342,6 +411,11 @@ function setupWailsListeners() {
     const { path, processes, env_loaded, env_count } = data;
     handleProcfileLoaded(path, processes, env_loaded, env_count);
   });
+
+  EventsOn("procfile-reloaded", (data) => {
+    console.log("procfile-reloaded event:", data);
+    handleProcfileReloaded(data);
+  });
 }
 
 // Open Procfile dialog
@@ -350,7 +424,21 @@ async function openProcfile() {
     const selected = await OpenFileDialog();
 
     if (selected) {
-      await loadProcfileWithPath(selected);
+      await openProject(selected);
+    }
+  } catch (err) {
+    console.error("Dialog error:", err);
+    setStatus(`Error: ${err}`, true);
+  }
+}
+
+// Open project folder dialog
+async function openFolder() {
+  try {
+    const selected = await OpenDirectoryDialog();
+
+    if (selected) {
+      await openProject(selected);
     }
   } catch (err) {
     console.error("Dialog error:", err);
@@ -358,6 +446,23 @@ async function openProcfile() {
   }
 }
 
+// Load the Procfile of a picked file or folder, or propose one to import
+// when the project has none yet
+async function openProject(path) {
+  const scan = await ScanProject(path);
+  if (scan.procfile) {
+    await loadProcfileWithPath(scan.procfile);
+    return;
+  }
+
+  state.importDir = scan.dir;
+  elements.procfileModalTitle.textContent = `New Procfile in ${scan.dir}`;
+  elements.procfileModalSave.textContent = "Create";
+  elements.procfileContent.value = scan.proposal;
+  elements.procfileModal.classList.remove("hidden");
+  elements.procfileContent.focus();
+}
+
 // Load procfile by path (stops all running processes first)
 async function loadProcfileWithPath(path) {
   try {
@@ -442,21 +547,24 @@ function handleProcfileLoaded(path, processes, envLoaded, envCount) {
   elements.procfilePath.textContent = path;
   elements.btnViewProcfile.classList.remove("hidden");
   elements.btnStartAll.disabled = false;
+  elements.exportFormat.disabled = false;
 
   // Initialize processes
   processes.forEach((proc, index) => {
     state.processes[proc.name] = {
       name: proc.name,
+      type: proc.type || proc.name,
       status: "stopped",
       color: PROCESS_COLORS[index % PROCESS_COLORS.length],
       exitCode: null,
+      restarts: 0,
       disabled: proc.disabled || false,
     };
   });
 
   renderProcessList();
   renderTabs();
-  clearLogs();
+  loadLogHistory();
   updateProcessCount();
   updateSearchCount();
 
@@ -471,13 +579,106 @@ function handleProcfileLoaded(path, processes, envLoaded, envCount) {
   setStatus(statusMsg);
 }
 
+// Handle the Procfile reloaded after a change on disk: unlike a load, keep
+// the state and logs of processes that still exist, and keep removed ones
+// listed while they run so they can still be stopped
+function handleProcfileReloaded({ path, processes, added, removed, changed, env_changed, restarted, stopped, error }) {
+  if (error) {
+    setStatus(`Reloading ${path.split(/[\\/]/).pop()} failed: ${error}`, true);
+    return;
+  }
+
+  const previous = state.processes;
+  const updated = {};
+  processes.forEach((proc, index) => {
+    const existing = previous[proc.name];
+    updated[proc.name] = existing
+      ? { ...existing, type: proc.type || proc.name, disabled: proc.disabled || false }
+      : {
+          name: proc.name,
+          type: proc.type || proc.name,
+          status: "stopped",
+          color: PROCESS_COLORS[index % PROCESS_COLORS.length],
+          exitCode: null,
+          restarts: 0,
+          disabled: proc.disabled || false,
+        };
+  });
+  Object.values(previous).forEach((proc) => {
+    if (!updated[proc.name] && proc.status !== "stopped" && proc.status !== "crashed") {
+      updated[proc.name] = proc;
+    }
+  });
+
+  state.processes = updated;
+  renderProcessList();
+  renderTabs();
+  updateProcessCount();
+
+  const parts = [
+    ["added", added],
+    ["removed", removed],
+    ["changed", changed],
+    [".env changed", env_changed],
+    ["restarted", restarted],
+    ["stopped", stopped],
+  ]
+    .filter(([, names]) => names && names.length > 0)
+    .map(([label, names]) => `${label} ${names.join(", ")}`);
+  setStatus(`Procfile reloaded: ${parts.length > 0 ? parts.join("; ") : "no changes"}`);
+}
+
+// Handle a process type scaled up or down: replace its instances in place,
+// keeping the state (status, color, logs) of instances that still exist
+function handleProcessScaled(type, instances) {
+  const previous = state.processes;
+  const entries = Object.values(previous);
+  const processes = {};
+  let inserted = false;
+
+  const insertInstances = () => {
+    instances.forEach((inst) => {
+      processes[inst.name] = previous[inst.name] || {
+        name: inst.name,
+        type: inst.type,
+        status: "stopped",
+        color: PROCESS_COLORS[(entries.length + Object.keys(processes).length) % PROCESS_COLORS.length],
+        exitCode: null,
+        restarts: 0,
+        disabled: false,
+      };
+    });
+    inserted = true;
+  };
+
+  entries.forEach((proc) => {
+    if (proc.type === type) {
+      if (!inserted) insertInstances();
+      return;
+    }
+    processes[proc.name] = proc;
+  });
+  if (!inserted) insertInstances();
+
+  state.processes = processes;
+  renderProcessList();
+  renderTabs();
+  updateProcessCount();
+  setStatus(`Scaled ${type} to ${instances.length}`);
+}
+
+// Number of instances of a process type
+function instanceCount(type) {
+  return Object.values(state.processes).filter((p) => p.type === type).length;
+}
+
 // Render process list in sidebar
 function renderProcessList() {
   elements.processList.innerHTML = "";
 
   Object.values(state.processes).forEach((process) => {
     const item = document.createElement("div");
-    const isRunning = process.status === "running";
+    const isRunning = isRunningStatus(process.status);
     const isDisabled = process.disabled;
     item.className = `process-item${isRunning ? " running" : ""}${isDisabled ? " disabled" : ""}`;
     item.dataset.process = process.name;
@@ -507,10 +708,13 @@ function renderProcessList() {
     } else {
       item.innerHTML = `
         <div class="flex items-center gap-2 flex-1 min-w-0">
-          <span class="status-dot ${process.status}" style="background-color: ${isRunning ? process.color : ''}"></span>
+          <span class="status-dot ${process.status}" style="background-color: ${hasProcessColor(process.status) ? process.color : ''}"></span>
           <span class="truncate">${process.name}</span>
+          ${process.restarts > 0 ? `<span class="text-xs ${process.status === "crashed" ? "text-red-400" : "text-yellow-500"}" title="Automatic restarts">↻${process.restarts}</span>` : ""}
         </div>
         <div class="process-actions">
+          <button class="action-btn" data-action="scale-down" title="Remove an instance of ${process.type}" ${instanceCount(process.type) > 1 ? "" : "disabled"}>−</button>
+          <button class="action-btn" data-action="scale-up" title="Add an instance of ${process.type}">+</button>
           <button class="action-btn" data-action="toggle-visibility" title="${isHidden ? 'Show' : 'Hide'} output">
             ${isHidden ? eyeOffIcon() : eyeIcon()}
           </button>
@@ -586,10 +790,12 @@ function setActiveTab(processName) {
   if (processName === "all") {
     elements.btnCopyPath.classList.add("hidden");
     elements.btnSaveLog.classList.add("hidden");
+    elements.stdinBar.classList.add("hidden");
     elements.askOpencodeBar.classList.add("hidden");
   } else {
     elements.btnCopyPath.classList.remove("hidden");
     elements.btnSaveLog.classList.remove("hidden");
+    elements.stdinBar.classList.remove("hidden");
     elements.askOpencodeBar.classList.remove("hidden");
   }
 
@@ -609,6 +815,13 @@ async function handleProcessAction(name, action) {
       case "restart":
         await RestartProcess(name);
         break;
+      case "scale-up":
+      case "scale-down": {
+        const type = state.processes[name].type;
+        const count = instanceCount(type) + (action === "scale-up" ? 1 : -1);
+        await ScaleProcess(type, count);
+        break;
+      }
       case "toggle-visibility":
         if (state.hiddenProcesses.has(name)) {
           state.hiddenProcesses.delete(name);
@@ -644,6 +857,22 @@ async function stopAllProcesses() {
   }
 }
 
+// Export the Procfile in the selected format to a directory the user picks
+async function exportProcfile() {
+  const format = elements.exportFormat.value;
+  elements.exportFormat.value = "";
+  if (!format) return;
+
+  try {
+    const paths = await ExportProcfile(format);
+    if (paths && paths.length > 0) {
+      setStatus(`Exported ${paths.length} file${paths.length === 1 ? "" : "s"} to ${paths[0].replace(/[\\/][^\\/]*$/, "")}`);
+    }
+  } catch (err) {
+    setStatus(`Error: ${err}`, true);
+  }
+}
+
 // Toggle auto-restart global setting
 async function toggleAutoRestart() {
   const enabled = elements.autoRestartToggle.checked;
@@ -655,43 +884,99 @@ async function toggleAutoRestart() {
   }
 }
 
+// Toggle reloading the Procfile on file changes and restarting changed processes
+async function toggleAutoReload() {
+  const enabled = elements.autoReloadToggle.checked;
+  const restart = elements.reloadRestartToggle.checked;
+  elements.reloadRestartToggle.disabled = !enabled;
+  try {
+    await SetAutoReload(enabled, restart);
+    setStatus(`Reload on file change ${enabled ? "enabled" : "disabled"}`);
+  } catch (err) {
+    setStatus(`Error: ${err}`, true);
+  }
+}
+
 // Save current process log to file
 async function saveCurrentLog() {
   if (state.activeTab === "all") return;
 
-  const processLogs = state.logs.filter((log) => log.name === state.activeTab);
-  if (processLogs.length === 0) {
-    setStatus("No logs to save");
-    return;
-  }
-
-  const content = processLogs.map((log) => log.line).join("\n");
-
   try {
-    const filePath = await SaveLog(state.activeTab, content);
+    // Empty content saves the backend log buffer of the process
+    const filePath = await SaveLog(state.activeTab, "");
     setStatus(`Log saved to ${filePath}`);
   } catch (err) {
     setStatus(`Error saving log: ${err}`, true);
   }
 }
 
-// Copy log path to clipboard
-async function copyLogPath() {
-  if (state.activeTab === "all") return;
+// --- Log files ---
 
-  const processLogs = state.logs.filter((log) => log.name === state.activeTab);
-  if (processLogs.length === 0) {
-    setStatus("No logs to copy path for");
+// Show the log files of the project (only those of the active process on a process tab)
+async function openLogFilesModal() {
+  let files;
+  try {
+    files = await ListLogFiles();
+  } catch (err) {
+    setStatus(`${err}`, true);
     return;
   }
 
-  const content = processLogs.map((log) => {
-    const cleanLine = log.line.replace(/\x1b\[[0-9;]*m/g, "");
-    return cleanLine;
-  }).join("\n");
+  if (state.activeTab !== "all") {
+    files = files.filter((file) => file.process === state.activeTab);
+  }
+
+  elements.logFilesList.innerHTML = "";
+  if (files.length === 0) {
+    elements.logFilesList.innerHTML = '<div class="text-xs text-gray-500 italic px-2 py-4">No log files yet</div>';
+  }
+
+  files.forEach((file) => {
+    const btn = document.createElement("button");
+    btn.className = "w-full text-left px-3 py-1.5 rounded text-sm transition flex items-center gap-2 text-gray-300 hover:bg-gray-700 hover:text-white";
+    btn.title = file.path;
+
+    const label = document.createElement("span");
+    label.className = "truncate flex-1 font-mono";
+    label.textContent = file.name;
+
+    const info = document.createElement("span");
+    info.className = "text-xs text-gray-500 whitespace-nowrap";
+    info.textContent = `${formatFileSize(file.size)} · ${new Date(file.mod_time).toLocaleString()}`;
+
+    btn.appendChild(label);
+    btn.appendChild(info);
+    btn.addEventListener("click", async () => {
+      try {
+        await OpenLogFile(file.path);
+        closeLogFilesModal();
+      } catch (err) {
+        setStatus(`Error opening log file: ${err}`, true);
+      }
+    });
+    elements.logFilesList.appendChild(btn);
+  });
+
+  elements.logFilesModal.classList.remove("hidden");
+}
+
+function closeLogFilesModal() {
+  elements.logFilesModal.classList.add("hidden");
+}
+
+// Format a byte count for display
+function formatFileSize(bytes) {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / 1024 / 1024).toFixed(1)} MB`;
+}
+
+// Copy log path to clipboard
+async function copyLogPath() {
+  if (state.activeTab === "all") return;
 
   try {
-    const filePath = await SaveLog(state.activeTab, content);
+    const filePath = await SaveLog(state.activeTab, "");
     await navigator.clipboard.writeText(filePath);
     setStatus(`Path copied: ${filePath}`);
   } catch (err) {
@@ -699,11 +984,22 @@ async function copyLogPath() {
   }
 }
 
+// Statuses of a live process (readiness checks move it from starting to ready or unhealthy)
+function isRunningStatus(status) {
+  return ["running", "starting", "ready", "unhealthy"].includes(status);
+}
+
+// Statuses shown with the process color instead of a status color
+function hasProcessColor(status) {
+  return status === "running" || status === "ready";
+}
+
 // Update process status
-function updateProcessStatus(name, status, exitCode) {
+function updateProcessStatus(name, status, exitCode, restarts = 0) {
   if (state.processes[name]) {
     state.processes[name].status = status;
     state.processes[name].exitCode = exitCode;
+    state.processes[name].restarts = restarts;
     renderProcessList();
     updateProcessCount();
 
@@ -714,7 +1010,7 @@ function updateProcessStatus(name, status, exitCode) {
 }
 
 // Add log line
-function addLogLine(name, line, isStderr = false) {
+function addLogLine(name, line, isStderr = false, time = null) {
   console.log("addLogLine called:", name, line, isStderr);
   const process = state.processes[name];
   if (!process) {
@@ -727,7 +1023,7 @@ function addLogLine(name, line, isStderr = false) {
     line,
     isStderr,
     color: process.color,
-    timestamp: new Date(),
+    timestamp: time ? new Date(time) : new Date(),
   });
 
   // Keep only last 10000 lines
@@ -891,8 +1187,36 @@ function renderLogs() {
   container.scrollTop = container.scrollHeight;
 }
 
+// Load buffered output from the backend (kept across reloads of the same Procfile)
+async function loadLogHistory() {
+  state.logs = [];
+  try {
+    const history = await GetLogs("", 0, 10000);
+    history.forEach((log) => {
+      const process = state.processes[log.name];
+      if (!process) return;
+      state.logs.push({
+        name: log.name,
+        line: log.line,
+        isStderr: log.is_stderr,
+        color: process.color,
+        timestamp: new Date(log.time),
+      });
+    });
+  } catch (err) {
+    console.error("Failed to load log history:", err);
+  }
+
+  if (state.logs.length === 0) {
+    elements.logOutput.innerHTML = '<div class="text-gray-500 italic">No processes running. Click "Start All" to begin.</div>';
+    return;
+  }
+  renderLogs();
+}
+
 // Clear logs
 function clearLogs() {
+  ClearLogs("").catch((err) => console.error("Failed to clear logs:", err));
   state.logs = [];
   elements.logOutput.innerHTML = '<div class="text-gray-500 italic">No processes running. Click "Start All" to begin.</div>';
 }
@@ -901,7 +1225,7 @@ function clearLogs() {
 function updateProcessCount() {
   const activeProcesses = Object.values(state.processes).filter((p) => !p.disabled);
   const total = activeProcesses.length;
-  const running = activeProcesses.filter((p) => p.status === "running").length;
+  const running = activeProcesses.filter((p) => isRunningStatus(p.status)).length;
   elements.processCount.textContent = `${running}/${total} running`;
 
   // Enable/disable Stop All button based on running processes
@@ -1209,26 +1533,10 @@ async function askOpenCode() {
     return;
   }
 
-  // Get last 200 log lines for the current process
-  const processLogs = state.logs
-    .filter((log) => log.name === state.activeTab)
-    .slice(-200)
-    .map((log) => {
-      const timestamp = state.showTimestamps ? `[${formatTimestamp(log.timestamp)}] ` : "";
-      // Strip ANSI codes for clean output
-      const cleanLine = log.line.replace(/\x1b\[[0-9;]*m/g, "");
-      return `${timestamp}${cleanLine}`;
-    })
-    .join("\n");
-
-  if (!processLogs) {
-    setStatus("No logs available for this process");
-    return;
-  }
-
   try {
     setStatus("Opening OpenCode...");
-    await AskOpenCode(state.activeTab, processLogs, question);
+    // Empty logs hands the last 200 buffered lines of the process to OpenCode
+    await AskOpenCode(state.activeTab, "", question);
     // Save the question and clear input
     state.lastOpenCodeQuestion = question;
     elements.askOpencodeInput.value = "";
@@ -1251,19 +1559,8 @@ async function copyOpenCodeQuestion() {
     return;
   }
 
-  const processLogs = state.logs
-    .filter((log) => log.name === state.activeTab)
-    .slice(-200)
-    .map((log) => log.line.replace(/\x1b\[[0-9;]*m/g, ""))
-    .join("\n");
-
-  if (!processLogs) {
-    setStatus("No logs available for this process");
-    return;
-  }
-
   try {
-    const filePath = await SaveLog(state.activeTab, processLogs);
+    const filePath = await SaveLog(state.activeTab, "");
     const text = `use this log ${filePath} to answer this question: ${question}`;
     await navigator.clipboard.writeText(text);
     state.lastOpenCodeQuestion = question;
@@ -1274,6 +1571,44 @@ async function copyOpenCodeQuestion() {
   }
 }
 
+// --- Process stdin ---
+
+// Send the stdin input line to the active process
+async function sendStdinLine() {
+  if (state.activeTab === "all") return;
+  const line = elements.stdinInput.value;
+  try {
+    await WriteStdin(state.activeTab, line + "\n");
+    elements.stdinInput.value = "";
+  } catch (err) {
+    setStatus(`Error: ${err}`, true);
+  }
+}
+
+// Send EOF to the active process
+async function sendStdinEOF() {
+  if (state.activeTab === "all") return;
+  try {
+    await CloseStdin(state.activeTab);
+  } catch (err) {
+    setStatus(`Error: ${err}`, true);
+  }
+}
+
+// Handle keydown on stdin input
+function handleStdinKeydown(e) {
+  if (e.key === "Enter") {
+    e.preventDefault();
+    sendStdinLine();
+    return;
+  }
+  // Ctrl+D on an empty input sends EOF, like a terminal
+  if (e.key === "d" && e.ctrlKey && elements.stdinInput.value === "") {
+    e.preventDefault();
+    sendStdinEOF();
+  }
+}
+
 // Handle keydown on Ask OpenCode input
 function handleAskOpencodeKeydown(e) {
   if (e.key === "Enter") {
@@ -1294,6 +1629,9 @@ async function loadSettings() {
   try {
     state.settings = await GetSettings();
     updateEditorButton();
+    elements.autoReloadToggle.checked = state.settings.autoReload !== "false";
+    elements.reloadRestartToggle.checked = state.settings.reloadRestart === "true";
+    elements.reloadRestartToggle.disabled = !elements.autoReloadToggle.checked;
   } catch (err) {
     console.error("Failed to load settings:", err);
   }
@@ -1423,11 +1761,28 @@ async function openProcfileModal() {
 
 function closeProcfileModal() {
   elements.procfileModal.classList.add("hidden");
+  state.importDir = null;
+  elements.procfileModalTitle.textContent = "Edit Procfile";
+  elements.procfileModalSave.textContent = "Save";
 }
 
 async function saveProcfileContent() {
   const content = elements.procfileContent.value;
 
+  if (state.importDir) {
+    const dir = state.importDir;
+    try {
+      await StopAllProcesses();
+      await ImportProcfile(dir, content);
+      closeProcfileModal();
+      renderRecentProjects(await AddRecentProject(dir));
+      setStatus("Procfile created and loaded");
+    } catch (err) {
+      setStatus(`Error creating Procfile: ${err}`, true);
+    }
+    return;
+  }
+
   try {
     await SaveProcfileContent(content);
     closeProcfileModal();
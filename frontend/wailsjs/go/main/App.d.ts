@@ -1,6 +1,7 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
+import {supervisor} from '../models';
 
 export function AddRecentProject(arg1:string):Promise<Array<string>>;
 
@@ -8,30 +9,48 @@ export function AskOpenCode(arg1:string,arg2:string,arg3:string):Promise<void>;
 
 export function CheckOpenCode():Promise<string>;
 
+export function ClearLogs(arg1:string):Promise<void>;
+
+export function CloseStdin(arg1:string):Promise<void>;
+
 export function EnableProcess(arg1:string):Promise<void>;
 
+export function ExportProcfile(arg1:string):Promise<Array<string>>;
+
 export function GetActivePorts():Promise<Array<main.PortInfo>>;
 
 export function GetAppIcon(arg1:string):Promise<string>;
 
 export function GetDemoProcfilePath():Promise<string>;
 
+export function GetEffectiveEnv(arg1:string):Promise<Array<supervisor.EnvVar>>;
+
 export function GetInstalledApps():Promise<Array<string>>;
 
+export function GetLogs(arg1:string,arg2:number,arg3:number):Promise<Array<supervisor.ProcessOutput>>;
+
 export function GetProcfileContent():Promise<string>;
 
 export function GetRecentProjects():Promise<Array<string>>;
 
 export function GetSettings():Promise<Record<string, string>>;
 
+export function ImportProcfile(arg1:string,arg2:string):Promise<void>;
+
 export function KillPort(arg1:number):Promise<void>;
 
+export function ListLogFiles():Promise<Array<supervisor.LogFileInfo>>;
+
 export function LoadProcfile(arg1:string):Promise<void>;
 
+export function OpenDirectoryDialog():Promise<string>;
+
 export function OpenFileDialog():Promise<string>;
 
 export function OpenFileInEditor(arg1:string,arg2:number):Promise<void>;
 
+export function OpenLogFile(arg1:string):Promise<void>;
+
 export function RestartProcess(arg1:string):Promise<void>;
 
 export function SaveLog(arg1:string,arg2:string):Promise<string>;
@@ -40,8 +59,20 @@ export function SaveProcfileContent(arg1:string):Promise<void>;
 
 export function SaveSetting(arg1:string,arg2:string):Promise<void>;
 
+export function ScaleProcess(arg1:string,arg2:number):Promise<void>;
+
+export function ScanProject(arg1:string):Promise<main.ProjectScan>;
+
+export function SearchLogs(arg1:string,arg2:boolean,arg3:Array<string>):Promise<Array<supervisor.ProcessOutput>>;
+
+export function SetAutoReload(arg1:boolean,arg2:boolean):Promise<void>;
+
 export function SetGlobalAutoRestart(arg1:boolean):Promise<void>;
 
+export function SetTerminalSize(arg1:number,arg2:number):Promise<void>;
+
+export function SignalProcess(arg1:string,arg2:string,arg3:boolean):Promise<void>;
+
 export function StartAllProcesses():Promise<void>;
 
 export function StartProcess(arg1:string):Promise<void>;
@@ -49,3 +80,5 @@ export function StartProcess(arg1:string):Promise<void>;
 export function StopAllProcesses():Promise<void>;
 
 export function StopProcess(arg1:string):Promise<void>;
+
+export function WriteStdin(arg1:string,arg2:string):Promise<void>;
@@ -14,10 +14,22 @@ export function CheckOpenCode() {
   return window['go']['main']['App']['CheckOpenCode']();
 }
 
+export function ClearLogs(arg1) {
+  return window['go']['main']['App']['ClearLogs'](arg1);
+}
+
+export function CloseStdin(arg1) {
+  return window['go']['main']['App']['CloseStdin'](arg1);
+}
+
 export function EnableProcess(arg1) {
   return window['go']['main']['App']['EnableProcess'](arg1);
 }
 
+export function ExportProcfile(arg1) {
+  return window['go']['main']['App']['ExportProcfile'](arg1);
+}
+
 export function GetActivePorts() {
   return window['go']['main']['App']['GetActivePorts']();
 }
@@ -30,10 +42,18 @@ export function GetDemoProcfilePath() {
   return window['go']['main']['App']['GetDemoProcfilePath']();
 }
 
+export function GetEffectiveEnv(arg1) {
+  return window['go']['main']['App']['GetEffectiveEnv'](arg1);
+}
+
 export function GetInstalledApps() {
   return window['go']['main']['App']['GetInstalledApps']();
 }
 
+export function GetLogs(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetLogs'](arg1, arg2, arg3);
+}
+
 export function GetProcfileContent() {
   return window['go']['main']['App']['GetProcfileContent']();
 }
@@ -46,14 +66,26 @@ export function GetSettings() {
   return window['go']['main']['App']['GetSettings']();
 }
 
+export function ImportProcfile(arg1, arg2) {
+  return window['go']['main']['App']['ImportProcfile'](arg1, arg2);
+}
+
 export function KillPort(arg1) {
   return window['go']['main']['App']['KillPort'](arg1);
 }
 
+export function ListLogFiles() {
+  return window['go']['main']['App']['ListLogFiles']();
+}
+
 export function LoadProcfile(arg1) {
   return window['go']['main']['App']['LoadProcfile'](arg1);
 }
 
+export function OpenDirectoryDialog() {
+  return window['go']['main']['App']['OpenDirectoryDialog']();
+}
+
 export function OpenFileDialog() {
   return window['go']['main']['App']['OpenFileDialog']();
 }
@@ -62,6 +94,10 @@ export function OpenFileInEditor(arg1, arg2) {
   return window['go']['main']['App']['OpenFileInEditor'](arg1, arg2);
 }
 
+export function OpenLogFile(arg1) {
+  return window['go']['main']['App']['OpenLogFile'](arg1);
+}
+
 export function RestartProcess(arg1) {
   return window['go']['main']['App']['RestartProcess'](arg1);
 }
@@ -78,10 +114,34 @@ export function SaveSetting(arg1, arg2) {
   return window['go']['main']['App']['SaveSetting'](arg1, arg2);
 }
 
+export function ScaleProcess(arg1, arg2) {
+  return window['go']['main']['App']['ScaleProcess'](arg1, arg2);
+}
+
+export function ScanProject(arg1) {
+  return window['go']['main']['App']['ScanProject'](arg1);
+}
+
+export function SearchLogs(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SearchLogs'](arg1, arg2, arg3);
+}
+
+export function SetAutoReload(arg1, arg2) {
+  return window['go']['main']['App']['SetAutoReload'](arg1, arg2);
+}
+
 export function SetGlobalAutoRestart(arg1) {
   return window['go']['main']['App']['SetGlobalAutoRestart'](arg1);
 }
 
+export function SetTerminalSize(arg1, arg2) {
+  return window['go']['main']['App']['SetTerminalSize'](arg1, arg2);
+}
+
+export function SignalProcess(arg1, arg2, arg3) {
+  return window['go']['main']['App']['SignalProcess'](arg1, arg2, arg3);
+}
+
 export function StartAllProcesses() {
   return window['go']['main']['App']['StartAllProcesses']();
 }
@@ -97,3 +157,7 @@ export function StopAllProcesses() {
 export function StopProcess(arg1) {
   return window['go']['main']['App']['StopProcess'](arg1);
 }
+
+export function WriteStdin(arg1, arg2) {
+  return window['go']['main']['App']['WriteStdin'](arg1, arg2);
+}
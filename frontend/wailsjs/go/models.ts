@@ -1,3 +1,26 @@
+export namespace importer {
+	
+	export class Candidate {
+	    name: string;
+	    command: string;
+	    source: string;
+	    active: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Candidate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.command = source["command"];
+	        this.source = source["source"];
+	        this.active = source["active"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
 	export class PortInfo {
@@ -18,6 +41,146 @@ export namespace main {
 	        this.command = source["command"];
 	    }
 	}
+	export class ProjectScan {
+	    dir: string;
+	    procfile: string;
+	    candidates: importer.Candidate[];
+	    proposal: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectScan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dir = source["dir"];
+	        this.procfile = source["procfile"];
+	        this.candidates = this.convertValues(source["candidates"], importer.Candidate);
+	        this.proposal = source["proposal"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
 
+export namespace supervisor {
+	
+	export class EnvVar {
+	    key: string;
+	    value: string;
+	    source: string;
+	    overrides?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new EnvVar(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.value = source["value"];
+	        this.source = source["source"];
+	        this.overrides = source["overrides"];
+	    }
+	}
+	export class LogFileInfo {
+	    name: string;
+	    process: string;
+	    path: string;
+	    size: number;
+	    // Go type: time
+	    mod_time: any;
+	    current: boolean;
+	    compressed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new LogFileInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.process = source["process"];
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.mod_time = this.convertValues(source["mod_time"], null);
+	        this.current = source["current"];
+	        this.compressed = source["compressed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProcessOutput {
+	    seq: number;
+	    // Go type: time
+	    time: any;
+	    name: string;
+	    line: string;
+	    is_stderr: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProcessOutput(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.seq = source["seq"];
+	        this.time = this.convertValues(source["time"], null);
+	        this.name = source["name"];
+	        this.line = source["line"];
+	        this.is_stderr = source["is_stderr"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
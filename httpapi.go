@@ -0,0 +1,356 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"procfile-runner/supervisor"
+)
+
+// httpTokenName is the bearer token file in the config dir
+const httpTokenName = "http_token"
+
+// httpPortSetting is the settings key that enables the HTTP API
+const httpPortSetting = "httpPort"
+
+// sseKeepAlive is how often an idle event stream gets a comment line, so
+// proxies and browsers don't drop it
+const sseKeepAlive = 15 * time.Second
+
+// getHTTPTokenPath returns the path of the HTTP API token file
+func getHTTPTokenPath() (string, error) {
+	configDir, err := getConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, httpTokenName), nil
+}
+
+// loadHTTPToken returns the token stored at path, creating a random one
+// readable only by the current user when there is none yet
+func loadHTTPToken(path string) (string, error) {
+	if data, err := os.ReadFile(path); err == nil {
+		if token := strings.TrimSpace(string(data)); len(token) >= 32 {
+			return token, nil
+		}
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return token, os.Chmod(path, 0600)
+}
+
+// httpServer serves the REST API and the event stream on 127.0.0.1
+type httpServer struct {
+	sup     *supervisor.Supervisor
+	token   string
+	server  *http.Server
+	streams map[*sseStream]bool
+	mu      sync.Mutex
+}
+
+// sseStream is a connected event stream client
+type sseStream struct {
+	events chan sseEvent
+	names  []string // process names to stream (all when empty)
+}
+
+// sseEvent is an event waiting to be sent to a stream
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+// newHTTPServer creates an HTTP API server; it serves once listen is called
+func newHTTPServer() *httpServer {
+	return &httpServer{streams: make(map[*sseStream]bool)}
+}
+
+// listen starts serving sup on 127.0.0.1:port, authorized by token
+func (h *httpServer) listen(port int, token string, sup *supervisor.Supervisor) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.sup = sup
+	h.token = token
+	h.server = &http.Server{Handler: h.handler(), ReadHeaderTimeout: 10 * time.Second}
+	server := h.server
+	h.mu.Unlock()
+
+	go server.Serve(listener)
+	return nil
+}
+
+// close stops serving and ends all event streams
+func (h *httpServer) close() {
+	h.mu.Lock()
+	server := h.server
+	h.server = nil
+	h.mu.Unlock()
+
+	if server != nil {
+		server.Close()
+	}
+}
+
+// handler returns the API routes behind the token check
+func (h *httpServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/processes", h.listProcesses)
+	mux.HandleFunc("POST /api/processes/{name}/{action}", h.processAction)
+	mux.HandleFunc("GET /api/logs", h.getLogs)
+	mux.HandleFunc("GET /api/ports", h.listPorts)
+	mux.HandleFunc("POST /api/ports/{port}/kill", h.killPort)
+	mux.HandleFunc("GET /api/procfile", h.getProcfile)
+	mux.HandleFunc("GET /api/events", h.streamEvents)
+	return h.authorize(mux)
+}
+
+// authorize rejects requests without the bearer token. EventSource can't
+// send headers, so the event stream also takes the token as ?token=; other
+// routes don't, to keep it out of logged URLs.
+func (h *httpServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var token string
+		if r.URL.Path == "/api/events" {
+			token = r.URL.Query().Get("token")
+		}
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		h.mu.Lock()
+		expected := h.token
+		h.mu.Unlock()
+
+		if expected == "" || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeJSONError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes an error as {"error": "..."}
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// listProcesses handles GET /api/processes
+func (h *httpServer) listProcesses(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.sup.Status())
+}
+
+// processAction handles POST /api/processes/{name}/start|stop|restart|signal.
+// signal takes {"signal": "HUP", "leader_only": false} as body.
+func (h *httpServer) processAction(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	var err error
+	switch r.PathValue("action") {
+	case "start":
+		err = h.sup.Start(name)
+	case "stop":
+		err = h.sup.Stop(name)
+	case "restart":
+		err = h.sup.Restart(name)
+	case "signal":
+		var body struct {
+			Signal     string `json:"signal"`
+			LeaderOnly bool   `json:"leader_only"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %v", err))
+			return
+		}
+		err = h.sup.Signal(name, body.Signal, body.LeaderOnly)
+	default:
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("unknown action %q", r.PathValue("action")))
+		return
+	}
+
+	if err != nil {
+		writeJSONError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// getLogs handles GET /api/logs?name=&since_seq=&limit=
+func (h *httpServer) getLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	sinceSeq, _ := strconv.ParseUint(query.Get("since_seq"), 10, 64)
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	writeJSON(w, http.StatusOK, h.sup.Logs(query.Get("name"), sinceSeq, limit))
+}
+
+// listPorts handles GET /api/ports
+func (h *httpServer) listPorts(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, activePorts())
+}
+
+// killPort handles POST /api/ports/{port}/kill
+func (h *httpServer) killPort(w http.ResponseWriter, r *http.Request) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("invalid port %q", r.PathValue("port")))
+		return
+	}
+	if err := killPort(port); err != nil {
+		writeJSONError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"ok": true})
+}
+
+// getProcfile handles GET /api/procfile
+func (h *httpServer) getProcfile(w http.ResponseWriter, r *http.Request) {
+	path := h.sup.ProcfilePath()
+	if path == "" {
+		writeJSONError(w, http.StatusNotFound, errors.New("no Procfile loaded"))
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"path": path, "content": string(content)})
+}
+
+// streamEvents handles GET /api/events?names=web,worker, a Server-Sent
+// Events stream of process-output and process-status
+func (h *httpServer) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+
+	stream := &sseStream{events: make(chan sseEvent, controlQueueSize)}
+	if names := r.URL.Query().Get("names"); names != "" {
+		stream.names = strings.Split(names, ",")
+	}
+	h.mu.Lock()
+	h.streams[stream] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.streams, stream)
+		h.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event := <-stream.events:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, event.data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// broadcast sends an event to the streams interested in the process.
+// Streams that can't keep up miss events rather than blocking the processes.
+func (h *httpServer) broadcast(event string, name string, data interface{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.streams) == 0 {
+		return
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	for stream := range h.streams {
+		if len(stream.names) > 0 && !subscribedTo(name, stream.names) {
+			continue
+		}
+		select {
+		case stream.events <- sseEvent{name: event, data: encoded}:
+		default:
+		}
+	}
+}
+
+// OnStatus streams process-status
+func (h *httpServer) OnStatus(status supervisor.ProcessStatus) {
+	h.broadcast("process-status", status.Name, status)
+}
+
+// OnOutput streams process-output
+func (h *httpServer) OnOutput(output supervisor.ProcessOutput) {
+	h.broadcast("process-output", output.Name, output)
+}
+
+// OnProcfileLoaded is not streamed; clients re-read /api/processes
+func (h *httpServer) OnProcfileLoaded(loaded supervisor.ProcfileLoaded) {}
+
+// OnScaled is not streamed; clients re-read /api/processes
+func (h *httpServer) OnScaled(scaled supervisor.ProcessScaled) {}
+
+// OnProcfileReloaded is not streamed; clients re-read /api/processes
+func (h *httpServer) OnProcfileReloaded(reloaded supervisor.ProcfileReloaded) {}
+
+// startHTTPAPI serves the HTTP API on port with the token from the config
+// dir and returns a note on where it listens
+func startHTTPAPI(h *httpServer, port int, sup *supervisor.Supervisor) (string, error) {
+	tokenPath, err := getHTTPTokenPath()
+	if err != nil {
+		return "", err
+	}
+	token, err := loadHTTPToken(tokenPath)
+	if err != nil {
+		return "", err
+	}
+	if err := h.listen(port, token, sup); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("HTTP API on http://127.0.0.1:%d (token in %s)", port, tokenPath), nil
+}
@@ -0,0 +1,322 @@
+// Package importer proposes a Procfile for a project that has none yet,
+// from its package.json scripts, Makefile targets, docker-compose services
+// and Procfile.* variants
+package importer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"procfile-runner/supervisor"
+)
+
+// Candidate is a process found in a project
+type Candidate struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	Source  string `json:"source"` // file it was found in, e.g. "package.json"
+	Active  bool   `json:"active"` // proposed enabled, otherwise commented out
+}
+
+// devScripts are script and target names that usually start the app in
+// development, in order of preference; the first one found is enabled
+var devScripts = []string{"dev", "start", "serve", "server", "run"}
+
+// composeFiles are the docker-compose file names, in compose's own order
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yml", "docker-compose.yaml"}
+
+// Scan looks for processes in dir. When a Procfile.* variant exists its
+// processes are the ones enabled; otherwise the dev script of package.json
+// and of the Makefile and all compose services are.
+func Scan(dir string) ([]Candidate, error) {
+	var candidates []Candidate
+
+	variants, err := scanVariants(dir)
+	if err != nil {
+		return nil, err
+	}
+	candidates = append(candidates, variants...)
+
+	scanners := []func(string) ([]Candidate, error){scanPackageJSON, scanMakefile, scanCompose}
+	for _, scan := range scanners {
+		found, err := scan(dir)
+		if err != nil {
+			return nil, err
+		}
+		for i := range found {
+			found[i].Active = found[i].Active && len(variants) == 0
+		}
+		candidates = append(candidates, found...)
+	}
+
+	return uniqueNames(candidates), nil
+}
+
+// Propose renders candidates as Procfile content, grouped by source, with
+// the inactive ones commented out (they show up as disabled processes)
+func Propose(candidates []Candidate) string {
+	var sb strings.Builder
+	sb.WriteString("# Procfile proposed by procfile-runner, review it before saving.\n")
+	sb.WriteString("# Commented-out processes show up disabled and can be enabled later.\n")
+	if len(candidates) == 0 {
+		sb.WriteString("# Nothing found to run, add one line per process as name, colon, command.\n")
+	}
+
+	source := ""
+	for _, candidate := range candidates {
+		if candidate.Source != source {
+			source = candidate.Source
+			fmt.Fprintf(&sb, "\n# From %s\n", source)
+		}
+		if !candidate.Active {
+			sb.WriteString("# ")
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", candidate.Name, candidate.Command)
+	}
+	return sb.String()
+}
+
+// scanVariants reads the processes of Procfile.dev, Procfile.local, ...
+func scanVariants(dir string) ([]Candidate, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "Procfile.*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var candidates []Candidate
+	for _, path := range paths {
+		// Side configs (Procfile.json, Procfile.dev.json) are not Procfiles
+		if strings.HasSuffix(path, ".json") {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		for _, def := range supervisor.ParseProcfile(string(content)) {
+			candidates = append(candidates, Candidate{
+				Name:    def.Name,
+				Command: def.Command,
+				Source:  filepath.Base(path),
+				Active:  !def.Disabled,
+			})
+		}
+	}
+	return candidates, nil
+}
+
+// lifecycleScripts are package.json scripts npm runs by itself
+var lifecycleScripts = map[string]bool{
+	"install": true, "preinstall": true, "postinstall": true,
+	"prepare": true, "prepublish": true, "prepublishOnly": true, "prepack": true, "postpack": true,
+}
+
+// lockfileRunners run package.json scripts with the package manager whose
+// lockfile is present
+var lockfileRunners = map[string]string{
+	"pnpm-lock.yaml": "pnpm run",
+	"yarn.lock":      "yarn run",
+	"bun.lockb":      "bun run",
+	"bun.lock":       "bun run",
+}
+
+// scanPackageJSON proposes `npm run <script>` (or the package manager the
+// lockfile points to) for each script
+func scanPackageJSON(dir string) ([]Candidate, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "package.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil, fmt.Errorf("package.json: %w", err)
+	}
+
+	runner := "npm run"
+	for _, lockfile := range []string{"pnpm-lock.yaml", "yarn.lock", "bun.lockb", "bun.lock"} {
+		if _, err := os.Stat(filepath.Join(dir, lockfile)); err == nil {
+			runner = lockfileRunners[lockfile]
+			break
+		}
+	}
+
+	var names []string
+	for name := range pkg.Scripts {
+		// Hooks like predev and postbuild run along with their script
+		base := strings.TrimPrefix(strings.TrimPrefix(name, "pre"), "post")
+		if lifecycleScripts[name] || (base != name && pkg.Scripts[base] != "") {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return withDevActive(names, func(name string) Candidate {
+		return Candidate{Name: name, Command: runner + " " + name, Source: "package.json"}
+	}), nil
+}
+
+// makeTarget matches a rule for a single plain target, not a variable
+// assignment (:= and ::=) or a pattern rule
+var makeTarget = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9_.-]*)\s*::?(?:[^=:]|$)`)
+
+// scanMakefile proposes `make <target>` for each target
+func scanMakefile(dir string) ([]Candidate, error) {
+	var file *os.File
+	var err error
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		if file, err = os.Open(filepath.Join(dir, name)); err == nil {
+			break
+		}
+	}
+	if file == nil {
+		return nil, nil
+	}
+	defer file.Close()
+
+	var names []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		match := makeTarget.FindStringSubmatch(scanner.Text())
+		if match == nil || seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+		names = append(names, match[1])
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	source := filepath.Base(file.Name())
+	return withDevActive(names, func(name string) Candidate {
+		return Candidate{Name: name, Command: "make " + name, Source: source}
+	}), nil
+}
+
+// scanCompose proposes `docker compose up <service>` for each service
+func scanCompose(dir string) ([]Candidate, error) {
+	for _, name := range composeFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+
+		var candidates []Candidate
+		for _, service := range composeServices(string(data)) {
+			candidates = append(candidates, Candidate{
+				Name:    service,
+				Command: fmt.Sprintf("docker compose -f %s up %s", name, service),
+				Source:  name,
+				Active:  true,
+			})
+		}
+		return candidates, nil
+	}
+	return nil, nil
+}
+
+// composeServices returns the keys under the top-level services: mapping.
+// It reads just enough YAML for that: keys one indentation level deeper.
+func composeServices(content string) []string {
+	var services []string
+	inServices := false
+	indent := -1
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		depth := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if depth == 0 {
+			inServices = strings.HasPrefix(trimmed, "services:")
+			continue
+		}
+		if !inServices {
+			continue
+		}
+		if indent < 0 {
+			indent = depth
+		}
+		if depth != indent {
+			continue
+		}
+		if name, _, found := strings.Cut(trimmed, ":"); found {
+			services = append(services, strings.Trim(name, `"'`))
+		}
+	}
+	return services
+}
+
+// withDevActive builds a candidate per name and enables the first dev
+// script found
+func withDevActive(names []string, candidate func(string) Candidate) []Candidate {
+	candidates := make([]Candidate, 0, len(names))
+	for _, name := range names {
+		candidates = append(candidates, candidate(name))
+	}
+
+	for _, dev := range devScripts {
+		for i := range candidates {
+			if candidates[i].Name == dev {
+				candidates[i].Active = true
+				return candidates
+			}
+		}
+	}
+	return candidates
+}
+
+// unsafeNameChars are not used in process names; a dot would read as an
+// instance number
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// uniqueNames makes the candidate names valid Procfile names and unique,
+// suffixing later duplicates with their source (e.g. "dev-make")
+func uniqueNames(candidates []Candidate) []Candidate {
+	used := make(map[string]bool)
+	for i, candidate := range candidates {
+		name := strings.Trim(unsafeNameChars.ReplaceAllString(candidate.Name, "-"), "-")
+		if name == "" {
+			name = "process"
+		}
+
+		if used[name] {
+			source := strings.ToLower(candidate.Source)
+			switch {
+			case strings.HasPrefix(source, "procfile."):
+				source = strings.TrimPrefix(source, "procfile.")
+			case strings.Contains(source, "compose"):
+				source = "compose"
+			case strings.Contains(source, "makefile"):
+				source = "make"
+			default:
+				source = "npm"
+			}
+			base := name + "-" + source
+			name = base
+			for n := 2; used[name]; n++ {
+				name = fmt.Sprintf("%s-%d", base, n)
+			}
+		}
+
+		used[name] = true
+		candidates[i].Name = name
+	}
+	return candidates
+}
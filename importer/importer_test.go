@@ -0,0 +1,111 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"procfile-runner/supervisor"
+)
+
+// writeFiles creates files in a temp dir and returns it
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"package.json": `{"scripts": {"dev": "vite", "build": "vite build", "predev": "echo", "postinstall": "patch", "lint:css": "stylelint"}}`,
+		"yarn.lock":    "",
+		"Makefile":     "BIN := app\nVERSION ?= 1\n.PHONY: run test\n\nrun: build\n\tgo run .\n\ntest:\n\tgo test ./...\n\n%.o: %.c\n\tcc $<\n\ndev::\n\tair\n",
+		"docker-compose.yml": `version: "3"
+services:
+  db:
+    image: postgres
+    ports:
+      - "5432:5432"
+  # cache is optional
+  redis:
+    image: redis
+volumes:
+  data:
+`,
+	})
+
+	candidates, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, c := range candidates {
+		state := "off"
+		if c.Active {
+			state = "on"
+		}
+		got = append(got, c.Name+"="+c.Command+"="+state)
+	}
+	expected := []string{
+		"build=yarn run build=off",
+		"dev=yarn run dev=on",
+		"lint-css=yarn run lint:css=off",
+		"run=make run=off",
+		"test=make test=off",
+		"dev-make=make dev=on",
+		"db=docker compose -f docker-compose.yml up db=on",
+		"redis=docker compose -f docker-compose.yml up redis=on",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected candidates:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+
+	// The proposal parses back into the same processes, inactive ones disabled
+	definitions := supervisor.ParseProcfile(Propose(candidates))
+	active := 0
+	for _, def := range definitions {
+		if !def.Disabled {
+			active++
+		}
+	}
+	if len(definitions) != len(candidates) || active != 4 {
+		t.Errorf("Expected %d processes with 4 active, got %+v", len(candidates), definitions)
+	}
+}
+
+func TestScanVariants(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"Procfile.dev":  "web: bin/rails server\n# worker: bin/jobs\n",
+		"Procfile.json": `{"processes": {}}`,
+		"package.json":  `{"scripts": {"dev": "vite"}}`,
+	})
+
+	candidates, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candidates) != 3 {
+		t.Fatalf("Expected web, worker and dev, got %+v", candidates)
+	}
+	if !candidates[0].Active || candidates[1].Active || candidates[0].Source != "Procfile.dev" {
+		t.Errorf("Expected the variant's own enabled processes, got %+v", candidates[:2])
+	}
+	if candidates[2].Active {
+		t.Error("Expected package.json scripts to stay disabled next to a Procfile variant")
+	}
+
+	if proposal := Propose(nil); len(supervisor.ParseProcfile(proposal)) != 0 {
+		t.Errorf("Expected an empty proposal without processes, got:\n%s", proposal)
+	}
+
+	if _, err := Scan(writeFiles(t, map[string]string{"package.json": "{"})); err == nil {
+		t.Error("Expected an error for an invalid package.json")
+	}
+}
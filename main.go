@@ -18,6 +18,11 @@ var assets embed.FS
 var demoProcfile string
 
 func main() {
+	// Headless subcommands run in the terminal without a window
+	if len(os.Args) > 1 && isCLICommand(os.Args[1]) {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 	app.demoProcfile = demoProcfile
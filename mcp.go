@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"procfile-runner/supervisor"
+)
+
+// mcpProtocolVersions are the Model Context Protocol revisions we speak,
+// newest first
+var mcpProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// mcpDefaultLines is how many log lines read_logs returns by default
+const mcpDefaultLines = 100
+
+// mcpTool describes a tool in tools/list
+type mcpTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+}
+
+// mcpToolResult is the result of tools/call
+type mcpToolResult struct {
+	Content []mcpContent `json:"content"`
+	IsError bool         `json:"isError,omitempty"`
+}
+
+// mcpContent is a text block of a tool result
+type mcpContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// mcpArguments are the arguments the tools accept
+type mcpArguments struct {
+	Name  string   `json:"name"`
+	Lines int      `json:"lines"`
+	Query string   `json:"query"`
+	Regex bool     `json:"regex"`
+	Names []string `json:"names"`
+}
+
+// mcpSchema builds a JSON schema for an object with the given properties
+func mcpSchema(required []string, properties map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// mcpNameProperty is the schema of a process name argument
+var mcpNameProperty = map[string]interface{}{
+	"type":        "string",
+	"description": "Process type (e.g. \"web\", all its instances) or instance name (e.g. \"web.2\")",
+}
+
+// mcpTools are the tools the server offers
+var mcpTools = []mcpTool{
+	{
+		Name:        "list_processes",
+		Description: "List the Procfile processes with their status (running, ready, starting, unhealthy, restarting, crashed, stopped, disabled), pid, last exit code and restart count.",
+		InputSchema: mcpSchema(nil, map[string]interface{}{}),
+	},
+	{
+		Name:        "read_logs",
+		Description: "Read recent output (stdout and stderr) of a process, or of all processes, with timestamps. With query, search the log history instead.",
+		InputSchema: mcpSchema(nil, map[string]interface{}{
+			"name":  mcpNameProperty,
+			"lines": map[string]interface{}{"type": "integer", "description": fmt.Sprintf("Number of most recent lines (default %d)", mcpDefaultLines)},
+			"query": map[string]interface{}{"type": "string", "description": "Only lines containing this text (case-insensitive)"},
+			"regex": map[string]interface{}{"type": "boolean", "description": "Treat query as a regular expression"},
+			"names": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "With query, only search these processes (all when empty)"},
+		}),
+	},
+	{
+		Name:        "start_process",
+		Description: "Start a stopped process.",
+		InputSchema: mcpSchema([]string{"name"}, map[string]interface{}{"name": mcpNameProperty}),
+	},
+	{
+		Name:        "stop_process",
+		Description: "Stop a running process (stop signal, then SIGKILL after its timeout).",
+		InputSchema: mcpSchema([]string{"name"}, map[string]interface{}{"name": mcpNameProperty}),
+	},
+	{
+		Name:        "restart_process",
+		Description: "Restart a process, e.g. after changing code or configuration.",
+		InputSchema: mcpSchema([]string{"name"}, map[string]interface{}{"name": mcpNameProperty}),
+	},
+	{
+		Name:        "list_ports",
+		Description: "List processes listening on TCP ports 3000-9000 with pid and command.",
+		InputSchema: mcpSchema(nil, map[string]interface{}{}),
+	},
+	{
+		Name:        "read_procfile",
+		Description: "Read the loaded Procfile.",
+		InputSchema: mcpSchema(nil, map[string]interface{}{}),
+	},
+}
+
+// mcpBackend is the session the MCP tools operate on
+type mcpBackend interface {
+	status() ([]supervisor.ProcessStatus, error)
+	logs(name string, limit int) ([]supervisor.ProcessOutput, error)
+	search(query string, regex bool, names []string) ([]supervisor.ProcessOutput, error)
+	control(method string, name string) error // StartProcess, StopProcess or RestartProcess
+	ports() ([]PortInfo, error)
+	procfile() (string, error)
+}
+
+// localBackend runs the processes in the MCP server itself
+type localBackend struct {
+	sup *supervisor.Supervisor
+}
+
+func (b localBackend) status() ([]supervisor.ProcessStatus, error) {
+	return b.sup.Status(), nil
+}
+
+func (b localBackend) logs(name string, limit int) ([]supervisor.ProcessOutput, error) {
+	return b.sup.Logs(name, 0, limit), nil
+}
+
+func (b localBackend) search(query string, regex bool, names []string) ([]supervisor.ProcessOutput, error) {
+	return b.sup.SearchLogs(query, regex, names)
+}
+
+func (b localBackend) control(method string, name string) error {
+	switch method {
+	case "StartProcess":
+		return b.sup.Start(name)
+	case "StopProcess":
+		return b.sup.Stop(name)
+	}
+	return b.sup.Restart(name)
+}
+
+func (b localBackend) ports() ([]PortInfo, error) {
+	return activePorts(), nil
+}
+
+func (b localBackend) procfile() (string, error) {
+	return procfileContent(b.sup)
+}
+
+// remoteBackend drives a running session through its control socket
+type remoteBackend struct {
+	client *ctlClient
+}
+
+func (b remoteBackend) status() ([]supervisor.ProcessStatus, error) {
+	var statuses []supervisor.ProcessStatus
+	return statuses, b.client.call("GetStatus", nil, &statuses)
+}
+
+func (b remoteBackend) logs(name string, limit int) ([]supervisor.ProcessOutput, error) {
+	var lines []supervisor.ProcessOutput
+	return lines, b.client.call("GetLogs", map[string]interface{}{"name": name, "limit": limit}, &lines)
+}
+
+func (b remoteBackend) search(query string, regex bool, names []string) ([]supervisor.ProcessOutput, error) {
+	var lines []supervisor.ProcessOutput
+	params := map[string]interface{}{"query": query, "regex": regex, "names": names}
+	return lines, b.client.call("SearchLogs", params, &lines)
+}
+
+func (b remoteBackend) control(method string, name string) error {
+	return b.client.call(method, map[string]string{"name": name}, nil)
+}
+
+func (b remoteBackend) ports() ([]PortInfo, error) {
+	var ports []PortInfo
+	return ports, b.client.call("GetActivePorts", nil, &ports)
+}
+
+func (b remoteBackend) procfile() (string, error) {
+	var content string
+	return content, b.client.call("GetProcfileContent", nil, &content)
+}
+
+// runMCP serves the Model Context Protocol on stdin/stdout. It drives the
+// running session when there is one, otherwise it loads the Procfile and
+// runs the processes itself until the client disconnects.
+func runMCP(args []string) int {
+	flags := flag.NewFlagSet("mcp", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	procfile := flags.String("f", "Procfile", "path to the Procfile")
+	if err := flags.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "mcp: %v\n\n%s", err, cliUsage)
+		return exitUsage
+	}
+
+	var backend mcpBackend
+	if path, err := getControlSocketPath(); err == nil {
+		if client, err := dialControl(path); err == nil {
+			defer client.close()
+			fmt.Fprintf(os.Stderr, "mcp: using the running session (%s)\n", path)
+			backend = remoteBackend{client: client}
+		}
+	}
+
+	if backend == nil {
+		path, err := filepath.Abs(*procfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitUsage
+		}
+		// stdout carries the protocol, so process events only go to the log buffer
+		sup := supervisor.New(supervisor.MultiSink{})
+		sup.SetSessionID("")
+		if err := sup.Load(path); err != nil {
+			fmt.Fprintf(os.Stderr, "mcp: %s: %v\n", *procfile, err)
+			return exitUsage
+		}
+		defer sup.Close()
+		fmt.Fprintf(os.Stderr, "mcp: no running session, managing %s\n", path)
+		backend = localBackend{sup: sup}
+	}
+
+	if err := (&mcpServer{backend: backend}).serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "mcp: %v\n", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// mcpServer answers MCP requests with the tools above
+type mcpServer struct {
+	backend mcpBackend
+}
+
+// serve handles newline-delimited JSON-RPC messages until in is closed
+func (m *mcpServer) serve(in io.Reader, out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var req rpcRequest
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			encoder.Encode(rpcFailure{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: err.Error()}})
+			continue
+		}
+
+		result, err := m.handle(req)
+		if len(req.ID) == 0 {
+			continue // notifications get no response
+		}
+		if err != nil {
+			rpcErr, ok := err.(*rpcError)
+			if !ok {
+				rpcErr = &rpcError{Code: rpcFailed, Message: err.Error()}
+			}
+			encoder.Encode(rpcFailure{JSONRPC: "2.0", ID: req.ID, Error: rpcErr})
+			continue
+		}
+		if err := encoder.Encode(rpcResult{JSONRPC: "2.0", ID: req.ID, Result: result}); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers one MCP request
+func (m *mcpServer) handle(req rpcRequest) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+
+		version := mcpProtocolVersions[0]
+		for _, supported := range mcpProtocolVersions {
+			if params.ProtocolVersion == supported {
+				version = supported
+			}
+		}
+		return map[string]interface{}{
+			"protocolVersion": version,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]string{"name": "procfile-runner", "version": mcpVersion()},
+			"instructions":    "Manages the processes of a Procfile (web servers, workers, ...). Use list_processes and read_logs to debug failures, restart_process after fixing them.",
+		}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		return map[string]interface{}{"tools": mcpTools}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		var args mcpArguments
+		if len(params.Arguments) > 0 {
+			if err := json.Unmarshal(params.Arguments, &args); err != nil {
+				return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+			}
+		}
+
+		text, err := m.callTool(params.Name, args)
+		var unknown *rpcError
+		if errors.As(err, &unknown) {
+			return nil, err
+		}
+		if err != nil {
+			return mcpToolResult{Content: []mcpContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+		}
+		return mcpToolResult{Content: []mcpContent{{Type: "text", Text: text}}}, nil
+	}
+
+	if strings.HasPrefix(req.Method, "notifications/") {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("unknown method %q", req.Method)}
+}
+
+// callTool runs a tool and returns its text output. Unknown tools and
+// missing arguments are protocol errors (*rpcError), anything else is a
+// tool failure the agent gets to see.
+func (m *mcpServer) callTool(name string, args mcpArguments) (string, error) {
+	requireName := func() error {
+		if args.Name == "" {
+			return &rpcError{Code: rpcInvalidParams, Message: "missing argument: name"}
+		}
+		return nil
+	}
+
+	switch name {
+	case "list_processes":
+		statuses, err := m.backend.status()
+		if err != nil {
+			return "", err
+		}
+		return mcpJSON(statuses)
+
+	case "read_logs":
+		var lines []supervisor.ProcessOutput
+		var err error
+		if args.Query != "" {
+			names := args.Names
+			if args.Name != "" {
+				names = append(names, args.Name)
+			}
+			lines, err = m.backend.search(args.Query, args.Regex, names)
+			if args.Lines > 0 && len(lines) > args.Lines {
+				lines = lines[len(lines)-args.Lines:]
+			}
+		} else {
+			limit := args.Lines
+			if limit <= 0 {
+				limit = mcpDefaultLines
+			}
+			lines, err = m.backend.logs(args.Name, limit)
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(lines) == 0 {
+			return "(no output)", nil
+		}
+		return supervisor.FormatLogLines(lines, true), nil
+
+	case "start_process", "stop_process", "restart_process":
+		if err := requireName(); err != nil {
+			return "", err
+		}
+		// The supervisor ignores unknown names; tell the agent instead
+		statuses, err := m.backend.status()
+		if err != nil {
+			return "", err
+		}
+		known := false
+		for _, status := range statuses {
+			known = known || subscribedTo(status.Name, []string{args.Name})
+		}
+		if !known {
+			return "", fmt.Errorf("unknown process %q, see list_processes", args.Name)
+		}
+
+		method := map[string]string{"start_process": "StartProcess", "stop_process": "StopProcess", "restart_process": "RestartProcess"}[name]
+		if err := m.backend.control(method, args.Name); err != nil {
+			return "", err
+		}
+		verb := strings.TrimSuffix(name, "_process")
+		return fmt.Sprintf("%s: %s requested, use list_processes and read_logs to follow it", args.Name, verb), nil
+
+	case "list_ports":
+		ports, err := m.backend.ports()
+		if err != nil {
+			return "", err
+		}
+		return mcpJSON(ports)
+
+	case "read_procfile":
+		return m.backend.procfile()
+	}
+	return "", &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown tool %q", name)}
+}
+
+// mcpJSON renders a tool result as indented JSON
+func mcpJSON(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	return string(data), err
+}
+
+// mcpVersion returns the module version of this build
+func mcpVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "dev"
+}
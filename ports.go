@@ -21,15 +21,25 @@ type PortInfo struct {
 
 // GetActivePorts scans for active ports in the range 3000-9000
 func (a *App) GetActivePorts() []PortInfo {
+	return activePorts()
+}
+
+// KillPort kills the process listening on the specified port
+func (a *App) KillPort(port int) error {
+	return killPort(port)
+}
+
+// activePorts scans for active ports in the range 3000-9000
+func activePorts() []PortInfo {
 	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
 		return getActivePortsUnix()
 	}
 	return []PortInfo{}
 }
 
-// KillPort kills the process listening on the specified port
-func (a *App) KillPort(port int) error {
-	ports := a.GetActivePorts()
+// killPort kills the process listening on the specified port
+func killPort(port int) error {
+	ports := activePorts()
 	for _, p := range ports {
 		if p.Port == port && p.PID > 0 {
 			// Kill the process
@@ -0,0 +1,269 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SortByDependencies returns the definitions in start order, dependencies
+// first. Procfile order is kept wherever dependencies allow it.
+// Unknown dependencies and dependency cycles are reported as errors.
+func SortByDependencies(definitions []ProcessDefinition) ([]ProcessDefinition, error) {
+	byName := make(map[string]ProcessDefinition, len(definitions))
+	for _, def := range definitions {
+		byName[def.Name] = def
+	}
+
+	for _, def := range definitions {
+		for _, dep := range def.DependsOn {
+			if _, exists := byName[dep]; !exists {
+				return nil, fmt.Errorf("process %q depends on unknown process %q", def.Name, dep)
+			}
+		}
+	}
+
+	sorted := make([]ProcessDefinition, 0, len(definitions))
+	placed := make(map[string]bool, len(definitions))
+
+	for len(sorted) < len(definitions) {
+		progress := false
+
+		// Place the first definition (in Procfile order) whose dependencies are all placed
+		for _, def := range definitions {
+			if placed[def.Name] {
+				continue
+			}
+
+			ready := true
+			for _, dep := range def.DependsOn {
+				if !placed[dep] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				sorted = append(sorted, def)
+				placed[def.Name] = true
+				progress = true
+				break
+			}
+		}
+
+		if !progress {
+			return nil, fmt.Errorf("dependency cycle: %s", findCycle(definitions, byName, placed))
+		}
+	}
+
+	return sorted, nil
+}
+
+// findCycle walks dependencies of the unplaced definitions and returns the
+// first cycle found, formatted as "a -> b -> a"
+func findCycle(definitions []ProcessDefinition, byName map[string]ProcessDefinition, placed map[string]bool) string {
+	for _, start := range definitions {
+		if placed[start.Name] {
+			continue
+		}
+
+		path := []string{start.Name}
+		seen := map[string]int{start.Name: 0}
+		current := start
+
+		for {
+			// Follow the first dependency that is still unplaced
+			next := ""
+			for _, dep := range current.DependsOn {
+				if !placed[dep] {
+					next = dep
+					break
+				}
+			}
+			if next == "" {
+				break
+			}
+
+			if i, visited := seen[next]; visited {
+				return strings.Join(append(path[i:], next), " -> ")
+			}
+
+			seen[next] = len(path)
+			path = append(path, next)
+			current = byName[next]
+		}
+	}
+
+	return "unknown"
+}
+
+// WithDependencies returns the given process types plus everything they
+// depend on, directly or indirectly, in start order
+func (s *Supervisor) WithDependencies(names []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	selected, err := s.withDependencies(names)
+	if err != nil {
+		return nil, err
+	}
+	var ordered []string
+	for _, name := range s.order {
+		if selected[name] {
+			ordered = append(ordered, name)
+		}
+	}
+	return ordered, nil
+}
+
+// withDependencies returns the given process types plus everything they
+// depend on as a set. Must be called with s.mu held.
+func (s *Supervisor) withDependencies(names []string) (map[string]bool, error) {
+	selected := make(map[string]bool)
+	var add func(name string) error
+	add = func(name string) error {
+		if selected[name] {
+			return nil
+		}
+		def, exists := s.processes[name]
+		if !exists {
+			return fmt.Errorf("unknown process %q", name)
+		}
+		selected[name] = true
+		for _, dep := range def.DependsOn {
+			if err := add(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		if err := add(name); err != nil {
+			return nil, err
+		}
+	}
+	return selected, nil
+}
+
+// dependencyTimeout is how long StartAll waits for a dependency to become ready
+const dependencyTimeout = 60 * time.Second
+
+// waitForDependencies blocks until all dependencies of a process are ready
+func (s *Supervisor) waitForDependencies(def ProcessDefinition) error {
+	for _, dep := range def.DependsOn {
+		if err := s.waitReady(dep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitReady blocks until every instance of the named process type is ready
+func (s *Supervisor) waitReady(name string) error {
+	s.mu.Lock()
+	instances := s.instancesOf(name)
+	s.mu.Unlock()
+
+	for _, instance := range instances {
+		if err := s.waitInstanceReady(instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitInstanceReady blocks until a process instance is ready, exits, or its
+// ready check times out
+func (s *Supervisor) waitInstanceReady(name string) error {
+	s.mu.Lock()
+	handle, exists := s.running[name]
+	s.mu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("dependency %s is not running", name)
+	}
+
+	timeout := dependencyTimeout
+	if handle.readyTimeout > 0 {
+		timeout = handle.readyTimeout
+	}
+
+	select {
+	case <-handle.ready:
+		return nil
+	case <-handle.done:
+		return fmt.Errorf("dependency %s exited before becoming ready", name)
+	case <-time.After(timeout):
+		return fmt.Errorf("dependency %s did not become ready within %s", name, timeout)
+	}
+}
+
+// stopWaves groups the running instances into waves that can be stopped in
+// parallel. A process type is only stopped once nothing still running
+// depends on it. Must be called with s.mu held.
+func (s *Supervisor) stopWaves() [][]string {
+	remaining := make(map[string]bool)
+	known := make(map[string]bool)
+	for _, typeName := range s.order {
+		for _, instance := range s.instancesOf(typeName) {
+			known[instance] = true
+			if _, running := s.running[instance]; running {
+				remaining[typeName] = true
+			}
+		}
+	}
+
+	// Processes not in the current Procfile (e.g. from a previous one) have no dependents
+	var leftovers []string
+	for name := range s.running {
+		if !known[name] {
+			leftovers = append(leftovers, name)
+		}
+	}
+
+	var waves [][]string
+	for len(remaining) > 0 {
+		var wave []string
+		for _, typeName := range s.order {
+			if !remaining[typeName] {
+				continue
+			}
+
+			needed := false
+			for other := range remaining {
+				for _, dep := range s.processes[other].DependsOn {
+					if dep == typeName && other != typeName {
+						needed = true
+					}
+				}
+			}
+			if !needed {
+				wave = append(wave, typeName)
+			}
+		}
+
+		// Cycles are rejected at load time, but never loop forever
+		if len(wave) == 0 {
+			for typeName := range remaining {
+				wave = append(wave, typeName)
+			}
+		}
+
+		var names []string
+		for _, typeName := range wave {
+			delete(remaining, typeName)
+			for _, instance := range s.instancesOf(typeName) {
+				if _, running := s.running[instance]; running {
+					names = append(names, instance)
+				}
+			}
+		}
+		waves = append(waves, names)
+	}
+
+	if len(leftovers) > 0 {
+		waves = append([][]string{leftovers}, waves...)
+	}
+	return waves
+}
@@ -0,0 +1,326 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envKey matches a variable name; dots and dashes are allowed like dotenv
+var envKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// envEscapes are the escape sequences of double-quoted values. An escaped $
+// is kept from interpolation.
+var envEscapes = map[byte]string{'n': "\n", 'r': "\r", 't': "\t", '"': `"`, '\\': `\`, '$': string(literalDollar)}
+
+// envEntry is a variable as written in a .env file
+type envEntry struct {
+	Key     string
+	Value   string
+	Line    int  // 1-based line the entry starts on
+	Literal bool // single-quoted, not interpolated
+}
+
+// ParseEnvFile reads and parses a .env file, returning a map of key-value
+// pairs. It follows the common dotenv rules:
+//
+//   - blank lines and lines starting with # are skipped
+//   - an optional `export` prefix (followed by spaces or tabs) is ignored
+//   - unquoted values end at ` #` (an inline comment) and are trimmed
+//   - single-quoted values are literal and may span lines
+//   - double-quoted values may span lines and support \n, \r, \t, \", \\ and \$
+//   - unquoted and double-quoted values expand ${VAR}, ${VAR:-default} and
+//     $VAR from other keys of the file, else the system environment
+//
+// Malformed lines and reference cycles are reported with their line
+// numbers, all of them at once. They fail the whole file rather than drop a
+// variable silently. Later definitions of a key win.
+func ParseEnvFile(path string) (map[string]string, error) {
+	return parseEnvFile(path, os.LookupEnv)
+}
+
+// parseEnvFile is ParseEnvFile resolving references the file doesn't define
+// with lookup
+func parseEnvFile(path string, lookup func(string) (string, bool)) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := parseEnv(path, string(data))
+	if err != nil {
+		return nil, err
+	}
+	if entries, err = interpolateEnv(path, entries, lookup); err != nil {
+		return nil, err
+	}
+
+	env := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		env[entry.Key] = entry.Value
+	}
+	return env, nil
+}
+
+// parseEnv parses .env content into entries in file order. name prefixes the
+// line numbers of errors.
+func parseEnv(name string, content string) ([]envEntry, error) {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	var entries []envEntry
+	var errs []error
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		// Only leading space is trimmed: a quoted value keeps its trailing
+		// space, also on the first line of a multiline value
+		line := strings.TrimLeft(lines[i], " \t")
+
+		// Skip empty lines and comments
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if rest, found := strings.CutPrefix(line, "export"); found && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimLeft(rest, " \t")
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found {
+			errs = append(errs, fmt.Errorf("%s:%d: expected KEY=value", name, lineNo))
+			continue
+		}
+		if !envKey.MatchString(key) {
+			errs = append(errs, fmt.Errorf("%s:%d: invalid variable name %q", name, lineNo, key))
+			continue
+		}
+
+		value = strings.TrimLeft(value, " \t")
+		if value == "" || (value[0] != '"' && value[0] != '\'') {
+			entries = append(entries, envEntry{Key: key, Value: unquotedValue(value), Line: lineNo})
+			continue
+		}
+
+		// Quoted values run until the closing quote, possibly lines later
+		quote := value[0]
+		text := value[1:]
+		var parsed, rest string
+		for {
+			parsed, rest, found = quotedValue(text, quote)
+			if found || i+1 >= len(lines) {
+				break
+			}
+			i++
+			text += "\n" + lines[i]
+		}
+		switch {
+		case !found:
+			errs = append(errs, fmt.Errorf("%s:%d: unterminated %c-quoted value of %s", name, lineNo, quote, key))
+		case !isEnvComment(rest):
+			errs = append(errs, fmt.Errorf("%s:%d: unexpected %q after the quoted value of %s", name, lineNo, strings.TrimSpace(rest), key))
+		default:
+			entries = append(entries, envEntry{Key: key, Value: parsed, Line: lineNo, Literal: quote == '\''})
+		}
+	}
+
+	return entries, errors.Join(errs...)
+}
+
+// unquotedValue strips an inline comment and surrounding whitespace from an
+// unquoted value. A # only starts a comment after whitespace, so URLs with
+// fragments keep theirs.
+func unquotedValue(value string) string {
+	for i := 1; i < len(value); i++ {
+		if value[i] == '#' && (value[i-1] == ' ' || value[i-1] == '\t') {
+			value = value[:i]
+			break
+		}
+	}
+	if strings.HasPrefix(value, "#") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
+
+// quotedValue reads a quoted value up to its closing quote and returns it
+// with the text after the quote. Single-quoted values are literal; double
+// quotes process escapes. found is false when the quote isn't closed yet.
+func quotedValue(text string, quote byte) (value string, rest string, found bool) {
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == quote:
+			return sb.String(), text[i+1:], true
+		case c == '\\' && quote == '"' && i+1 < len(text):
+			i++
+			if escaped, ok := envEscapes[text[i]]; ok {
+				sb.WriteString(escaped)
+			} else {
+				// Unknown escapes are kept as written
+				sb.WriteByte('\\')
+				sb.WriteByte(text[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", "", false
+}
+
+// isEnvComment reports whether the text after a quoted value is empty or a
+// comment
+func isEnvComment(rest string) bool {
+	rest = strings.TrimSpace(rest)
+	return rest == "" || strings.HasPrefix(rest, "#")
+}
+
+// defaultEnvFiles are the env files loaded when the side config names none,
+// lowest precedence first
+var defaultEnvFiles = []string{".env", ".env.local"}
+
+// EnvVar is a variable of a process environment with the source it came from
+type EnvVar struct {
+	Key       string   `json:"key"`
+	Value     string   `json:"value"`
+	Source    string   `json:"source"`              // env file path, "Procfile.json (web)", "system" or "supervisor"
+	Overrides []string `json:"overrides,omitempty"` // sources whose value this one replaced, lowest precedence first
+}
+
+// EnvFile is a loaded env file
+type EnvFile struct {
+	Path  string `json:"path"`
+	Count int    `json:"count"` // variables defined in the file
+}
+
+// envLayer holds the variables of one source; later layers win
+type envLayer struct {
+	source string
+	vars   map[string]string
+}
+
+// FindEnvFiles resolves env file names against the directory of the
+// procfile and returns the ones that exist, in order
+func FindEnvFiles(procfilePath string, names []string) []string {
+	var found []string
+	for _, path := range envFilePaths(procfilePath, names) {
+		if _, err := os.Stat(path); err == nil {
+			found = append(found, path)
+		}
+	}
+	return found
+}
+
+// envFilePaths resolves env file names against the directory of the
+// procfile; absolute names are kept
+func envFilePaths(procfilePath string, names []string) []string {
+	dir := filepath.Dir(procfilePath)
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		paths = append(paths, name)
+	}
+	return paths
+}
+
+// loadEnvLayers parses env files in order on top of base. References a file
+// doesn't define resolve against the layers before it, then lookup.
+func loadEnvLayers(paths []string, base []envLayer, lookup func(string) (string, bool)) ([]envLayer, error) {
+	layers := base
+	for _, path := range paths {
+		merged := mergeEnvLayers(layers)
+		vars, err := parseEnvFile(path, func(name string) (string, bool) {
+			if value, ok := merged[name]; ok {
+				return value, true
+			}
+			return lookup(name)
+		})
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers[:len(layers):len(layers)], envLayer{source: path, vars: vars})
+	}
+	return layers, nil
+}
+
+// processEnvLayers loads the env files and variables of a process on top of
+// base, the layers of the global env files. The process's variables expand
+// references against its files, then lookup. It returns only the layers of
+// the process.
+func processEnvLayers(procfilePath string, def ProcessDefinition, base []envLayer, lookup func(string) (string, bool)) ([]envLayer, error) {
+	layers, err := loadEnvLayers(FindEnvFiles(procfilePath, def.EnvFiles), base, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(def.Env) > 0 {
+		merged := mergeEnvLayers(layers)
+		expand := func(name string) (string, bool, error) {
+			if value, ok := merged[name]; ok {
+				return value, true, nil
+			}
+			value, ok := lookup(name)
+			return value, ok, nil
+		}
+		vars := make(map[string]string, len(def.Env))
+		for key, value := range def.Env {
+			expanded, err := expandVars(value, expand)
+			if err != nil {
+				return nil, fmt.Errorf("process %q: env %s: %w", def.Name, key, err)
+			}
+			vars[key] = expanded
+		}
+		source := fmt.Sprintf("%s (%s)", filepath.Base(procfilePath)+".json", def.Name)
+		layers = append(layers, envLayer{source: source, vars: vars})
+	}
+
+	return layers[len(base):], nil
+}
+
+// loadedEnvFiles returns the paths of env file layers
+func loadedEnvFiles(layers []envLayer) []string {
+	paths := make([]string, 0, len(layers))
+	for _, layer := range layers {
+		paths = append(paths, layer.source)
+	}
+	return paths
+}
+
+// mergeEnvLayers returns the variables of all layers, later layers winning
+func mergeEnvLayers(layers []envLayer) map[string]string {
+	merged := make(map[string]string)
+	for _, layer := range layers {
+		for key, value := range layer.vars {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// envVarsOf returns the variables of layers sorted by key, each with the
+// layer it came from and the layers it overrides
+func envVarsOf(layers []envLayer) []EnvVar {
+	index := make(map[string]int)
+	var vars []EnvVar
+	for _, layer := range layers {
+		for key, value := range layer.vars {
+			i, exists := index[key]
+			if !exists {
+				index[key] = len(vars)
+				vars = append(vars, EnvVar{Key: key, Value: value, Source: layer.source})
+				continue
+			}
+			vars[i].Overrides = append(vars[i].Overrides, vars[i].Source)
+			vars[i].Value = value
+			vars[i].Source = layer.source
+		}
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Key < vars[j].Key })
+	return vars
+}
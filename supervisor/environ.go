@@ -0,0 +1,88 @@
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultEnvAllowlist are the system variables a clean environment keeps
+var defaultEnvAllowlist = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "TERM", "TMPDIR", "TZ", "LANG", "LC_*",
+	// Windows needs these to start most programs
+	"SYSTEMROOT", "COMSPEC", "PATHEXT", "TEMP", "TMP", "USERPROFILE", "APPDATA", "LOCALAPPDATA",
+}
+
+// systemEnvLayer returns the system environment as a layer. With clean, only
+// the variables in allowlist (plus the default allowlist) are kept; a
+// trailing * matches any suffix.
+func systemEnvLayer(clean bool, allowlist []string) envLayer {
+	patterns := append(defaultEnvAllowlist[:len(defaultEnvAllowlist):len(defaultEnvAllowlist)], allowlist...)
+
+	vars := make(map[string]string)
+	for _, pair := range os.Environ() {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			continue
+		}
+		if clean && !envAllowed(key, patterns) {
+			continue
+		}
+		vars[key] = value
+	}
+	return envLayer{source: "system", vars: vars}
+}
+
+// envAllowed reports whether key matches one of the allowlist patterns
+func envAllowed(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// buildEnv flattens layers into a KEY=value list for exec.Cmd. Later layers
+// win and every key appears once, so children that read the first or the
+// last duplicate see the same value. The list is sorted by key.
+func buildEnv(layers []envLayer) []string {
+	merged := mergeEnvLayers(layers)
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+merged[key])
+	}
+	return env
+}
+
+// instanceEnvLayers returns the environment layers of a process instance in
+// precedence order: the system environment, the env files, the process's
+// own env files and variables, and the supervisor's PORT, PS and session
+// tag. Must be called with s.mu held.
+func (s *Supervisor) instanceEnvLayers(def ProcessDefinition, instance int) []envLayer {
+	supervisorVars := map[string]string{
+		"PORT": strconv.Itoa(s.instancePort(def, instance)),
+		"PS":   fmt.Sprintf("%s.%d", def.Name, instance),
+	}
+	if s.sessionID != "" {
+		supervisorVars[ProcessRunnerEnvKey] = s.sessionID
+	}
+
+	layers := []envLayer{systemEnvLayer(s.cleanEnv, s.envAllowlist)}
+	layers = append(layers, s.envLayers...)
+	layers = append(layers, s.processEnv[def.Name]...)
+	return append(layers, envLayer{source: "supervisor", vars: supervisorVars})
+}
@@ -0,0 +1,49 @@
+package supervisor
+
+// EventSink receives everything the supervisor reports about its processes.
+// Methods are called from process goroutines and must not block for long.
+type EventSink interface {
+	OnStatus(status ProcessStatus)
+	OnOutput(output ProcessOutput)
+	OnProcfileLoaded(loaded ProcfileLoaded)
+	OnScaled(scaled ProcessScaled)
+	OnProcfileReloaded(reloaded ProcfileReloaded)
+}
+
+// MultiSink passes every event on to several sinks in order
+type MultiSink []EventSink
+
+// OnStatus passes a status change on
+func (m MultiSink) OnStatus(status ProcessStatus) {
+	for _, sink := range m {
+		sink.OnStatus(status)
+	}
+}
+
+// OnOutput passes a line of output on
+func (m MultiSink) OnOutput(output ProcessOutput) {
+	for _, sink := range m {
+		sink.OnOutput(output)
+	}
+}
+
+// OnProcfileLoaded passes a loaded Procfile on
+func (m MultiSink) OnProcfileLoaded(loaded ProcfileLoaded) {
+	for _, sink := range m {
+		sink.OnProcfileLoaded(loaded)
+	}
+}
+
+// OnScaled passes a scale change on
+func (m MultiSink) OnScaled(scaled ProcessScaled) {
+	for _, sink := range m {
+		sink.OnScaled(scaled)
+	}
+}
+
+// OnProcfileReloaded passes a reload on
+func (m MultiSink) OnProcfileReloaded(reloaded ProcfileReloaded) {
+	for _, sink := range m {
+		sink.OnProcfileReloaded(reloaded)
+	}
+}
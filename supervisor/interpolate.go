@@ -0,0 +1,263 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// literalDollar stands for an escaped \$ in a parsed value until it has been
+// interpolated; env values can't contain NUL bytes otherwise
+const literalDollar = '\x00'
+
+// lookupFunc returns the value of a variable and whether it is set
+type lookupFunc func(name string) (string, bool, error)
+
+// expandVars replaces ${VAR}, ${VAR:-default} and $VAR in s with values from
+// lookup. Unset variables expand to nothing, a default is used when the
+// variable is unset or empty and may itself contain references. A $ that
+// doesn't start a reference (like $( or $1) is kept as written.
+func expandVars(s string, lookup lookupFunc) (string, error) {
+	if !strings.ContainsAny(s, "$\x00") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == literalDollar {
+			sb.WriteByte('$')
+			continue
+		}
+		if c != '$' || i+1 >= len(s) {
+			sb.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated ${ in %q", s)
+			}
+			name, fallback, hasDefault := strings.Cut(s[i+2:end], ":-")
+			if !isVarName(name) {
+				return "", fmt.Errorf("invalid variable name %q in %q", name, s)
+			}
+			value, _, err := lookup(name)
+			if err != nil {
+				return "", err
+			}
+			if value == "" && hasDefault {
+				if value, err = expandVars(fallback, lookup); err != nil {
+					return "", err
+				}
+			}
+			sb.WriteString(value)
+			i = end
+			continue
+		}
+
+		n := varNameLength(s[i+1:])
+		if n == 0 {
+			sb.WriteByte(c)
+			continue
+		}
+		value, _, err := lookup(s[i+1 : i+1+n])
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(value)
+		i += n
+	}
+	return sb.String(), nil
+}
+
+// expandCommand expands the variable references in a shell command like
+// expandVars, but respects the shell's quoting: references inside single
+// quotes (awk '{print $NF}') and escaped ones (\$HOME) are left for the
+// shell as written.
+func expandCommand(command string, lookup lookupFunc) (string, error) {
+	if !strings.Contains(command, "$") {
+		return command, nil
+	}
+
+	var sb strings.Builder
+	single, double := false, false
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+		switch {
+		case single:
+			single = c != '\''
+		case c == '\\' && i+1 < len(command):
+			sb.WriteByte(c)
+			i++
+			c = command[i]
+		case c == '\'' && !double:
+			single = true
+		case c == '"':
+			double = !double
+		case c == '$' && i+1 < len(command):
+			n := 1 + varNameLength(command[i+1:])
+			if command[i+1] == '{' {
+				end := closingBrace(command, i+2)
+				if end < 0 {
+					return "", fmt.Errorf("unterminated ${ in %q", command)
+				}
+				n = end + 1 - i
+			}
+			if n == 1 {
+				break
+			}
+			value, err := expandVars(command[i:i+n], lookup)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+			i += n - 1
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), nil
+}
+
+// envLookup looks variables up in an environment list of KEY=value pairs,
+// where later pairs win
+func envLookup(env []string) lookupFunc {
+	return func(name string) (string, bool, error) {
+		for i := len(env) - 1; i >= 0; i-- {
+			if value, ok := strings.CutPrefix(env[i], name+"="); ok {
+				return value, true, nil
+			}
+		}
+		return "", false, nil
+	}
+}
+
+// closingBrace returns the index of the } closing a ${ whose contents start
+// at start, skipping nested ${...} in defaults, or -1
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '$' && i+1 < len(s) && s[i+1] == '{':
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// varNameLength returns the length of the shell variable name s starts with
+func varNameLength(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		letter := c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// isVarName reports whether name is a valid shell variable name
+func isVarName(name string) bool {
+	return name != "" && varNameLength(name) == len(name)
+}
+
+// interpolateEnv expands the variable references in the values of .env
+// entries, except single-quoted ones. A reference resolves to the closest
+// earlier definition of the key, else to a later one, else to lookup (the
+// session vars and the system environment); a key referring to itself, like
+// PATH=$PATH:bin, reads the earlier value. Reference cycles are reported
+// with line numbers. name prefixes the errors.
+func interpolateEnv(name string, entries []envEntry, lookup func(string) (string, bool)) ([]envEntry, error) {
+	const (
+		pending = iota
+		resolving
+		resolved
+	)
+	state := make([]int, len(entries))
+	failed := make([]bool, len(entries))
+	var chain []int // entries being resolved, for the cycle message
+
+	var resolve func(i int) error
+	// definition returns the entry a reference to key from entry i reads
+	definition := func(i int, key string) int {
+		for j := i - 1; j >= 0; j-- {
+			if entries[j].Key == key {
+				return j
+			}
+		}
+		if key == entries[i].Key {
+			return -1
+		}
+		for j := len(entries) - 1; j > i; j-- {
+			if entries[j].Key == key {
+				return j
+			}
+		}
+		return -1
+	}
+	resolve = func(i int) error {
+		switch {
+		case failed[i]:
+			return errEnvReference
+		case state[i] == resolved:
+			return nil
+		case state[i] == resolving:
+			var keys []string
+			for _, j := range chain[slices.Index(chain, i):] {
+				keys = append(keys, entries[j].Key)
+			}
+			return fmt.Errorf("%s:%d: %s refers to itself through %s", name, entries[i].Line, entries[i].Key, strings.Join(append(keys, entries[i].Key), " -> "))
+		}
+
+		state[i] = resolving
+		chain = append(chain, i)
+		value := entries[i].Value
+		var err error
+		if !entries[i].Literal {
+			value, err = expandVars(value, func(key string) (string, bool, error) {
+				j := definition(i, key)
+				if j < 0 {
+					outer, ok := lookup(key)
+					return outer, ok, nil
+				}
+				if err := resolve(j); err != nil {
+					return "", false, err
+				}
+				return entries[j].Value, true, nil
+			})
+		}
+		chain = chain[:len(chain)-1]
+		state[i] = resolved
+		if err != nil {
+			failed[i] = true
+			if !errors.Is(err, errEnvReference) && !strings.HasPrefix(err.Error(), name+":") {
+				err = fmt.Errorf("%s:%d: %w", name, entries[i].Line, err)
+			}
+			return err
+		}
+		entries[i].Value = value
+		return nil
+	}
+
+	var errs []error
+	for i := range entries {
+		if err := resolve(i); err != nil && !errors.Is(err, errEnvReference) {
+			errs = append(errs, err)
+		}
+	}
+	return entries, errors.Join(errs...)
+}
+
+// errEnvReference marks a reference to an entry whose own error was already
+// reported
+var errEnvReference = errors.New("reference to a broken variable")
@@ -0,0 +1,108 @@
+package supervisor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// maxLineLength is the longest output line kept; the rest is replaced by a marker
+const maxLineLength = 32 * 1024
+
+// readLines reads r line by line and calls emit for every line until EOF.
+// Lines of any length are handled: bytes beyond maxLen are dropped and noted
+// with a marker. Carriage returns (progress bars, spinners) overwrite the
+// line like a terminal does, so only the last non-empty segment is emitted.
+// A final line without newline is emitted too. Returns the first read error
+// other than io.EOF.
+func readLines(r io.Reader, maxLen int, emit func(line string)) error {
+	reader := bufio.NewReader(r)
+
+	var segment []byte // current \r-separated segment of the line
+	var dropped int    // bytes of the segment cut off beyond maxLen
+	var last string    // last finished non-empty segment of the line
+	started := false   // whether the current line has any data yet
+
+	finishSegment := func() {
+		if len(segment) > 0 || dropped > 0 {
+			last = formatSegment(segment, dropped)
+		}
+		segment = segment[:0]
+		dropped = 0
+	}
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(chunk) > 0 {
+			started = true
+		}
+
+		endOfLine := err == nil
+		data := chunk
+		if endOfLine {
+			data = data[:len(data)-1]
+		}
+
+		// Split on \r; each \r starts the line over
+		for len(data) > 0 {
+			i := bytes.IndexByte(data, '\r')
+			part := data
+			if i >= 0 {
+				part = data[:i]
+			}
+			segment, dropped = appendLimited(segment, dropped, part, maxLen)
+			if i < 0 {
+				break
+			}
+			finishSegment()
+			data = data[i+1:]
+		}
+
+		// Emit on newline, or when the stream ends mid-line
+		if endOfLine || (err != nil && err != bufio.ErrBufferFull && started) {
+			finishSegment()
+			emit(last)
+			last = ""
+			started = false
+		}
+
+		switch {
+		case err == nil, err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF:
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// appendLimited appends part to segment, keeping at most maxLen bytes and
+// counting the rest as dropped. Cuts never split a UTF-8 character.
+func appendLimited(segment []byte, dropped int, part []byte, maxLen int) ([]byte, int) {
+	if dropped > 0 {
+		return segment, dropped + len(part)
+	}
+
+	room := maxLen - len(segment)
+	if room >= len(part) {
+		return append(segment, part...), dropped
+	}
+	if room < 0 {
+		room = 0
+	}
+	for room > 0 && !utf8.RuneStart(part[room]) {
+		room--
+	}
+	return append(segment, part[:room]...), len(part) - room
+}
+
+// formatSegment renders a line segment with a marker for truncated bytes
+func formatSegment(segment []byte, dropped int) string {
+	if dropped == 0 {
+		return string(segment)
+	}
+	return fmt.Sprintf("%s … [%d bytes truncated]", segment, dropped)
+}
@@ -0,0 +1,228 @@
+package supervisor
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	logBufferCapacity = 10000 // lines kept per process
+	maxSearchResults  = 1000  // most recent matches returned by SearchLogs
+)
+
+// ansiPattern matches ANSI escape sequences (colors, cursor movement)
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]`)
+
+// logRing is a fixed-size ring of output lines of one process
+type logRing struct {
+	lines []ProcessOutput
+	start int // index of the oldest line once the ring is full
+}
+
+// logBuffer keeps the recent output of every process. Lines get a sequence
+// number that increases across all processes, so merged views stay ordered
+// and clients can page forward from the last line they saw.
+type logBuffer struct {
+	mu       sync.Mutex
+	seq      uint64
+	capacity int
+	rings    map[string]*logRing
+}
+
+// newLogBuffer creates a log buffer keeping capacity lines per process
+func newLogBuffer(capacity int) *logBuffer {
+	return &logBuffer{
+		capacity: capacity,
+		rings:    make(map[string]*logRing),
+	}
+}
+
+// add stamps a line with the next sequence number and capture time and stores it
+func (b *logBuffer) add(output ProcessOutput) ProcessOutput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	output.Seq = b.seq
+	output.Time = time.Now()
+
+	ring := b.rings[output.Name]
+	if ring == nil {
+		ring = &logRing{}
+		b.rings[output.Name] = ring
+	}
+	if len(ring.lines) < b.capacity {
+		ring.lines = append(ring.lines, output)
+	} else {
+		ring.lines[ring.start] = output
+		ring.start = (ring.start + 1) % b.capacity
+	}
+
+	return output
+}
+
+// collect returns the buffered lines of the processes matching names (all
+// processes when empty) that satisfy keep, ordered by sequence number
+func (b *logBuffer) collect(names []string, keep func(ProcessOutput) bool) []ProcessOutput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var result []ProcessOutput
+	for name, ring := range b.rings {
+		if !logNameMatches(name, names) {
+			continue
+		}
+		for i := range ring.lines {
+			line := ring.lines[(ring.start+i)%len(ring.lines)]
+			if keep(line) {
+				result = append(result, line)
+			}
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Seq < result[j].Seq })
+	return result
+}
+
+// clear drops all buffered lines, or only those of the given process
+func (b *logBuffer) clear(name string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if name == "" {
+		b.rings = make(map[string]*logRing)
+		return
+	}
+	for ringName := range b.rings {
+		if logNameMatches(ringName, []string{name}) {
+			delete(b.rings, ringName)
+		}
+	}
+}
+
+// logNameMatches reports whether the output of instance name is selected by
+// names. An empty list selects everything; a process type selects all of its
+// instances ("web" selects "web.1" and "web.2").
+func logNameMatches(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if name == n {
+			return true
+		}
+		if rest, found := strings.CutPrefix(name, n+"."); found {
+			if _, err := strconv.Atoi(rest); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StripANSI removes ANSI escape sequences (colors, cursor movement) from a line
+func StripANSI(line string) string {
+	return ansiPattern.ReplaceAllString(line, "")
+}
+
+// FormatLogLines renders buffered lines as plain text without ANSI codes,
+// optionally prefixed with their capture time
+func FormatLogLines(lines []ProcessOutput, withTime bool) string {
+	var sb strings.Builder
+	for i, line := range lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		if withTime {
+			sb.WriteString(line.Time.Format("[15:04:05.000] "))
+		}
+		sb.WriteString(StripANSI(line.Line))
+	}
+	return sb.String()
+}
+
+// emitOutput records a line of process output, writes it to the log file
+// (if enabled) and sends it to the event sink. Secrets are masked first, so
+// they never reach the buffer, the log files or the sink.
+func (s *Supervisor) emitOutput(name string, line string, isStderr bool) {
+	output := s.logs.add(ProcessOutput{
+		Name:     name,
+		Line:     s.redactor.Load().redact(line),
+		IsStderr: isStderr,
+	})
+	s.sink.OnOutput(output)
+
+	if err := s.logFiles.write(output); err != nil {
+		s.emitOutput(name, fmt.Sprintf("Cannot write log file: %v", err), true)
+	}
+}
+
+// Logs returns buffered output of a process (an instance, or all instances
+// of a type; all processes when name is empty). With sinceSeq 0 the most
+// recent limit lines are returned; otherwise the first limit lines after
+// sinceSeq, so callers can page forward by passing the last Seq they saw.
+// A limit of 0 returns everything.
+func (s *Supervisor) Logs(name string, sinceSeq uint64, limit int) []ProcessOutput {
+	var names []string
+	if name != "" {
+		names = []string{name}
+	}
+
+	lines := s.logs.collect(names, func(line ProcessOutput) bool {
+		return line.Seq > sinceSeq
+	})
+
+	if limit > 0 && len(lines) > limit {
+		if sinceSeq == 0 {
+			lines = lines[len(lines)-limit:]
+		} else {
+			lines = lines[:limit]
+		}
+	}
+	if lines == nil {
+		lines = []ProcessOutput{}
+	}
+	return lines
+}
+
+// SearchLogs returns buffered lines of the given processes (all when empty)
+// containing query, case-insensitively, or matching it as a regular
+// expression. At most the 1000 most recent matches are returned.
+func (s *Supervisor) SearchLogs(query string, regex bool, names []string) ([]ProcessOutput, error) {
+	var match func(string) bool
+	if regex {
+		re, err := regexp.Compile(query)
+		if err != nil {
+			return nil, fmt.Errorf("invalid search pattern: %w", err)
+		}
+		match = re.MatchString
+	} else {
+		query = strings.ToLower(query)
+		match = func(line string) bool {
+			return strings.Contains(strings.ToLower(line), query)
+		}
+	}
+
+	lines := s.logs.collect(names, func(line ProcessOutput) bool {
+		return match(ansiPattern.ReplaceAllString(line.Line, ""))
+	})
+
+	if len(lines) > maxSearchResults {
+		lines = lines[len(lines)-maxSearchResults:]
+	}
+	if lines == nil {
+		lines = []ProcessOutput{}
+	}
+	return lines, nil
+}
+
+// ClearLogs drops buffered output of a process, or of all processes when
+// name is empty
+func (s *Supervisor) ClearLogs(name string) {
+	s.logs.clear(name)
+}
@@ -0,0 +1,400 @@
+package supervisor
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultLogDir        = "log"
+	defaultLogMaxSizeMB  = 10
+	defaultLogMaxAgeDays = 7
+)
+
+// LogFileOptions enables writing process output to log files under the project
+type LogFileOptions struct {
+	Dir        string `json:"dir,omitempty"`          // directory relative to the Procfile (default "log")
+	MaxSizeMB  int    `json:"max_size_mb,omitempty"`  // rotate once a file grows beyond this (default 10)
+	MaxAgeDays int    `json:"max_age_days,omitempty"` // delete rotated files older than this (default 7)
+	Compress   bool   `json:"compress,omitempty"`     // gzip rotated files
+}
+
+// LogFileInfo describes a current or rotated log file
+type LogFileInfo struct {
+	Name       string    `json:"name"`    // file name, e.g. "web-20260102-150405.log.gz"
+	Process    string    `json:"process"` // process instance the file belongs to
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+	Current    bool      `json:"current"` // the file being written to
+	Compressed bool      `json:"compressed"`
+}
+
+// rotatingLog appends lines to <dir>/<name>.log and rotates it by size and day
+type rotatingLog struct {
+	mu      sync.Mutex
+	name    string
+	dir     string
+	opts    LogFileOptions
+	file    *os.File
+	size    int64
+	started time.Time // when the current file was started
+	failing bool      // the last write failed (errors are reported once)
+}
+
+// logFileSet holds the log files of all processes of the loaded Procfile
+type logFileSet struct {
+	mu    sync.Mutex
+	dir   string         // absolute log directory, empty when disabled
+	opts  LogFileOptions // options with defaults applied
+	files map[string]*rotatingLog
+	temp  []string // unpacked copies of compressed files, removed on close
+}
+
+// configure switches log files on (opts set) or off for a project, closing
+// the files of the previous configuration
+func (s *logFileSet) configure(procfilePath string, opts *LogFileOptions) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, f := range s.files {
+		f.close()
+	}
+	s.files = make(map[string]*rotatingLog)
+	s.dir = ""
+
+	if opts == nil {
+		return
+	}
+
+	s.opts = *opts
+	if s.opts.Dir == "" {
+		s.opts.Dir = defaultLogDir
+	}
+	if s.opts.MaxSizeMB <= 0 {
+		s.opts.MaxSizeMB = defaultLogMaxSizeMB
+	}
+	if s.opts.MaxAgeDays <= 0 {
+		s.opts.MaxAgeDays = defaultLogMaxAgeDays
+	}
+
+	s.dir = s.opts.Dir
+	if !filepath.IsAbs(s.dir) {
+		s.dir = filepath.Join(getParentDir(procfilePath), s.dir)
+	}
+}
+
+// write appends a line to the log file of a process, if log files are enabled
+func (s *logFileSet) write(output ProcessOutput) error {
+	s.mu.Lock()
+	if s.dir == "" {
+		s.mu.Unlock()
+		return nil
+	}
+	f := s.files[output.Name]
+	if f == nil {
+		f = &rotatingLog{name: output.Name, dir: s.dir, opts: s.opts}
+		s.files[output.Name] = f
+	}
+	s.mu.Unlock()
+
+	return f.write(output)
+}
+
+// close closes all open log files
+func (s *logFileSet) close() {
+	s.configure("", nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, path := range s.temp {
+		os.Remove(path)
+	}
+	s.temp = nil
+}
+
+// write appends a timestamped line, rotating the file first when needed.
+// Only the first of a series of failed writes returns an error.
+func (l *rotatingLog) write(output ProcessOutput) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	err := l.writeLine(output)
+	if err != nil && l.failing {
+		return nil
+	}
+	l.failing = err != nil
+	return err
+}
+
+// writeLine formats and appends a line. Must be called with l.mu held.
+func (l *rotatingLog) writeLine(output ProcessOutput) error {
+	stream := "out"
+	if output.IsStderr {
+		stream = "err"
+	}
+	line := fmt.Sprintf("%s %s | %s\n", output.Time.Format("2006-01-02 15:04:05.000"), stream, ansiPattern.ReplaceAllString(output.Line, ""))
+
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			return err
+		}
+	}
+	if l.needsRotation(int64(len(line)), output.Time) {
+		l.rotate()
+		if err := l.open(); err != nil {
+			return err
+		}
+	}
+
+	n, err := l.file.WriteString(line)
+	l.size += int64(n)
+	return err
+}
+
+// needsRotation reports whether the current file is full or from a previous day
+func (l *rotatingLog) needsRotation(lineSize int64, now time.Time) bool {
+	if l.size > 0 && l.size+lineSize > int64(l.opts.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	y1, m1, d1 := l.started.Date()
+	y2, m2, d2 := now.Date()
+	return y1 != y2 || m1 != m2 || d1 != d2
+}
+
+// open opens (or continues) the current log file
+func (l *rotatingLog) open() error {
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return err
+	}
+
+	path := filepath.Join(l.dir, l.name+".log")
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	l.file = file
+	l.size = 0
+	l.started = time.Now()
+	if info, err := file.Stat(); err == nil {
+		l.size = info.Size()
+		if l.size > 0 {
+			l.started = info.ModTime()
+		}
+	}
+	return nil
+}
+
+// rotate moves the current file aside as <name>-<timestamp>.log, compresses
+// it if configured and removes rotated files past the maximum age
+func (l *rotatingLog) rotate() {
+	l.file.Close()
+	l.file = nil
+
+	current := filepath.Join(l.dir, l.name+".log")
+	rotated := filepath.Join(l.dir, fmt.Sprintf("%s-%s.log", l.name, time.Now().Format("20060102-150405")))
+	if err := os.Rename(current, rotated); err != nil {
+		return
+	}
+
+	opts, dir, name := l.opts, l.dir, l.name
+	go func() {
+		if opts.Compress {
+			compressFile(rotated)
+		}
+		removeOldLogs(dir, name, time.Duration(opts.MaxAgeDays)*24*time.Hour)
+	}()
+}
+
+// close closes the current file
+func (l *rotatingLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file != nil {
+		l.file.Close()
+		l.file = nil
+	}
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".gz")
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// removeOldLogs deletes rotated log files of a process older than maxAge
+func removeOldLogs(dir string, name string, maxAge time.Duration) {
+	files, err := listLogFiles(dir)
+	if err != nil {
+		return
+	}
+
+	for _, f := range files {
+		if f.Process == name && !f.Current && time.Since(f.ModTime) > maxAge {
+			os.Remove(f.Path)
+		}
+	}
+}
+
+// listLogFiles returns the current and rotated log files in dir, newest first
+func listLogFiles(dir string) ([]LogFileInfo, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []LogFileInfo{}, nil
+		}
+		return nil, err
+	}
+
+	files := []LogFileInfo{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		name := entry.Name()
+		base, compressed := strings.CutSuffix(name, ".gz")
+		base, isLog := strings.CutSuffix(base, ".log")
+		if !isLog {
+			continue
+		}
+
+		// Rotated files are named <process>-YYYYMMDD-HHMMSS.log
+		process, current := base, true
+		if len(base) > 16 && base[len(base)-16] == '-' {
+			if _, err := time.Parse("20060102-150405", base[len(base)-15:]); err == nil {
+				process, current = base[:len(base)-16], false
+			}
+		}
+
+		files = append(files, LogFileInfo{
+			Name:       name,
+			Process:    process,
+			Path:       filepath.Join(dir, name),
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Current:    current,
+			Compressed: compressed,
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].ModTime.After(files[j].ModTime) })
+	return files, nil
+}
+
+// ListLogFiles returns the log files of the loaded project, newest first
+func (s *Supervisor) ListLogFiles() ([]LogFileInfo, error) {
+	s.logFiles.mu.Lock()
+	dir := s.logFiles.dir
+	s.logFiles.mu.Unlock()
+
+	if dir == "" {
+		return nil, fmt.Errorf("log files are not enabled (add \"logs\" to the Procfile options)")
+	}
+	return listLogFiles(dir)
+}
+
+// ReadableLogFile checks that path is a log file of the loaded project and
+// returns a path that can be opened as text: compressed files are unpacked
+// to a temp file first, which is removed when the supervisor closes
+func (s *Supervisor) ReadableLogFile(path string) (string, error) {
+	files, err := s.ListLogFiles()
+	if err != nil {
+		return "", err
+	}
+
+	var file *LogFileInfo
+	for i := range files {
+		if files[i].Path == path {
+			file = &files[i]
+		}
+	}
+	if file == nil {
+		return "", fmt.Errorf("not a log file of this project: %s", path)
+	}
+
+	if file.Compressed {
+		return s.logFiles.unpack(file.Path)
+	}
+	return file.Path, nil
+}
+
+// unpack unpacks a gzipped log file into a new temp file that close removes
+func (s *logFileSet) unpack(path string) (string, error) {
+	tmpPath, err := decompressToTemp(path)
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.temp = append(s.temp, tmpPath)
+	s.mu.Unlock()
+	return tmpPath, nil
+}
+
+// decompressToTemp unpacks a gzipped log file into a temp file of its own,
+// so concurrent reads don't share one and other users can't plant it
+func decompressToTemp(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	zr, err := gzip.NewReader(src)
+	if err != nil {
+		return "", err
+	}
+	defer zr.Close()
+
+	// Keep the name at the end, so viewers still see the .log extension
+	dst, err := os.CreateTemp("", "procfile-runner-*-"+strings.TrimSuffix(filepath.Base(path), ".gz"))
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, zr)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
@@ -0,0 +1,136 @@
+package supervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ProcessOptions holds per-process settings from the Procfile side config
+type ProcessOptions struct {
+	DependsOn []string    `json:"depends_on,omitempty"` // processes that must be ready first
+	Ready     *ReadyCheck `json:"ready,omitempty"`      // how to tell the process is ready
+
+	Restart         string `json:"restart,omitempty"`           // never, on-failure or always (default: global auto-restart toggle)
+	RestartDelay    int    `json:"restart_delay,omitempty"`     // initial backoff in seconds, doubled per restart (default 2)
+	RestartMaxDelay int    `json:"restart_max_delay,omitempty"` // backoff cap in seconds (default 30)
+	MaxRestarts     int    `json:"max_restarts,omitempty"`      // restarts allowed within restart_window before "crashed" (default 5)
+	RestartWindow   int    `json:"restart_window,omitempty"`    // crash-loop window in seconds (default 60)
+
+	StopSignal  string `json:"stop_signal,omitempty"`  // TERM, INT or QUIT (default TERM)
+	StopTimeout int    `json:"stop_timeout,omitempty"` // seconds to wait for exit before SIGKILL (default 5)
+
+	PTY bool `json:"pty,omitempty"` // run on a pseudo-terminal (Linux only) for tools that need a TTY
+
+	Scale int `json:"scale,omitempty"` // number of instances to run (default 1)
+	Port  int `json:"port,omitempty"`  // base PORT for this process instead of base_port + 100 per position
+
+	Expand bool `json:"expand,omitempty"` // expand $VAR and ${VAR:-default} in the command before running it, except single-quoted or escaped ones
+
+	EnvFiles []string          `json:"env_files,omitempty"` // env files of this process, over the global ones
+	Env      map[string]string `json:"env,omitempty"`       // variables of this process, over its env files
+}
+
+// ProcfileOptions is the side config stored next to a Procfile as <Procfile>.json
+//
+// Example Procfile.json:
+//
+//	{
+//	  "processes": {
+//	    "db":  { "ready": { "tcp": "5432" } },
+//	    "web": { "depends_on": ["db"], "ready": { "http": "http://localhost:3000/up" } }
+//	  },
+//	  "formation": "web=2"
+//	}
+type ProcfileOptions struct {
+	Processes map[string]ProcessOptions `json:"processes"`
+	Formation string                    `json:"formation,omitempty"` // foreman-style counts, e.g. "web=3,worker=2"
+	BasePort  int                       `json:"base_port,omitempty"` // first PORT handed out (default: PORT from .env, else 5000)
+	Logs      *LogFileOptions           `json:"logs,omitempty"`      // write process output to log files when set
+
+	ExpandCommands bool     `json:"expand_commands,omitempty"` // set expand for every process
+	EnvFiles       []string `json:"env_files,omitempty"`       // env files merged in order, later ones win (default .env, .env.local)
+	CleanEnv       bool     `json:"clean_env,omitempty"`       // don't pass the system environment on, except PATH, HOME and the like
+	EnvAllowlist   []string `json:"env_allowlist,omitempty"`   // further system variables kept by clean_env, e.g. "SSH_AUTH_SOCK" or "AWS_*"
+
+	Redact *RedactOptions `json:"redact,omitempty"` // secrets masked in output (default: values of secret-looking env keys)
+}
+
+// FindOptionsFile looks for the side config of a procfile (e.g. Procfile.json)
+func FindOptionsFile(procfilePath string) string {
+	optionsPath := procfilePath + ".json"
+
+	if _, err := os.Stat(optionsPath); err == nil {
+		return optionsPath
+	}
+
+	return ""
+}
+
+// ParseOptionsFile reads and parses a Procfile side config
+func ParseOptionsFile(path string) (*ProcfileOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts ProcfileOptions
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return &opts, nil
+}
+
+// ApplyOptions copies per-process options onto the matching definitions
+func ApplyOptions(definitions []ProcessDefinition, opts *ProcfileOptions) error {
+	if opts == nil {
+		return nil
+	}
+
+	index := make(map[string]int, len(definitions))
+	for i, def := range definitions {
+		index[def.Name] = i
+	}
+
+	for name, processOpts := range opts.Processes {
+		i, exists := index[name]
+		if !exists {
+			return fmt.Errorf("options given for unknown process %q", name)
+		}
+		if !validRestartPolicy(processOpts.Restart) {
+			return fmt.Errorf("process %q: unknown restart policy %q", name, processOpts.Restart)
+		}
+		if processOpts.StopSignal != "" {
+			if _, err := parseStopSignal(processOpts.StopSignal); err != nil {
+				return fmt.Errorf("process %q: invalid stop_signal: %w", name, err)
+			}
+		}
+		if processOpts.Ready != nil && processOpts.Ready.Log != "" {
+			if _, err := regexp.Compile(processOpts.Ready.Log); err != nil {
+				return fmt.Errorf("process %q: invalid ready log pattern: %w", name, err)
+			}
+		}
+		for key := range processOpts.Env {
+			if !envKey.MatchString(key) {
+				return fmt.Errorf("process %q: invalid env variable name %q", name, key)
+			}
+		}
+		definitions[i].ProcessOptions = processOpts
+	}
+
+	// Catch malformed references now rather than at every start
+	for i, def := range definitions {
+		if opts.ExpandCommands {
+			definitions[i].Expand = true
+		}
+		if definitions[i].Expand {
+			if _, err := expandCommand(def.Command, envLookup(nil)); err != nil {
+				return fmt.Errorf("process %q: %w", def.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package supervisor
+
+import "time"
+
+// Plan is the loaded Procfile with every option resolved to the value the
+// supervisor uses, for turning it into another process manager's config
+type Plan struct {
+	Path      string            `json:"path"`
+	Dir       string            `json:"dir"` // working directory of the processes
+	Env       map[string]string `json:"env"` // variables from the env files
+	Processes []PlannedProcess  `json:"processes"`
+}
+
+// PlannedProcess is an enabled process type of a Plan
+type PlannedProcess struct {
+	Name          string            `json:"name"`
+	Command       string            `json:"command"`
+	DependsOn     []string          `json:"depends_on,omitempty"`
+	Ready         *ReadyCheck       `json:"ready,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`  // the process's own env files and variables, over Plan.Env
+	Count         int               `json:"count"`          // instances, see Instances
+	BasePort      int               `json:"base_port"`      // PORT of the first instance, +1 per further instance
+	Restart       string            `json:"restart"`        // never, on-failure or always
+	RestartDelay  time.Duration     `json:"restart_delay"`  // backoff before the first restart
+	MaxRestarts   int               `json:"max_restarts"`   // restarts allowed within RestartWindow
+	RestartWindow time.Duration     `json:"restart_window"` // crash-loop window
+	StopSignal    string            `json:"stop_signal"`    // e.g. "SIGTERM"
+	StopTimeout   time.Duration     `json:"stop_timeout"`   // grace period before SIGKILL
+}
+
+// Plan returns the loaded Procfile in start order, without disabled
+// processes. A process without a restart policy follows the global
+// auto-restart toggle, as it does when run here.
+func (s *Supervisor) Plan() Plan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := Plan{
+		Path: s.procfilePath,
+		Dir:  getParentDir(s.procfilePath),
+		Env:  make(map[string]string, len(s.envVars)),
+	}
+	for key, value := range s.envVars {
+		plan.Env[key] = value
+	}
+
+	for _, name := range s.order {
+		def := s.processes[name]
+		if def.Disabled {
+			continue
+		}
+
+		process := PlannedProcess{
+			Name:          def.Name,
+			Command:       def.Command,
+			DependsOn:     def.DependsOn,
+			Ready:         def.Ready,
+			Env:           mergeEnvLayers(s.processEnv[name]),
+			Count:         max(s.formation[name], 1),
+			BasePort:      s.ports[name],
+			Restart:       s.restartPolicy(def),
+			RestartDelay:  restartDelay(def.ProcessOptions, 0),
+			MaxRestarts:   defaultMaxRestarts,
+			RestartWindow: defaultRestartWindow,
+			StopSignal:    "SIGTERM",
+			StopTimeout:   defaultStopTimeout,
+		}
+		if def.MaxRestarts > 0 {
+			process.MaxRestarts = def.MaxRestarts
+		}
+		if def.RestartWindow > 0 {
+			process.RestartWindow = time.Duration(def.RestartWindow) * time.Second
+		}
+		if sig, err := parseStopSignal(def.StopSignal); err == nil {
+			process.StopSignal = SignalName(sig)
+		}
+		if def.StopTimeout > 0 {
+			process.StopTimeout = time.Duration(def.StopTimeout) * time.Second
+		}
+		plan.Processes = append(plan.Processes, process)
+	}
+	return plan
+}
+
+// Instances returns the instance names of the process, like
+// Supervisor.Instances does while it is loaded
+func (p PlannedProcess) Instances() []string {
+	return instanceNames(p.Name, p.Count)
+}
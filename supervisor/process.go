@@ -0,0 +1,602 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ProcessRunnerEnvKey is the environment variable used to tag our child processes
+const ProcessRunnerEnvKey = "PROCFILE_RUNNER_SESSION"
+
+// defaultStopTimeout is how long a process gets to exit after its stop signal
+const defaultStopTimeout = 5 * time.Second
+
+// outputDrainTimeout is how long an exited process's remaining output is
+// awaited (background children may keep its pipes open)
+const outputDrainTimeout = 2 * time.Second
+
+// ProcessHandle holds information about a running process
+type ProcessHandle struct {
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+	pgid   int           // process group ID for killing children
+	pty    *os.File      // pseudo-terminal master in PTY mode, nil otherwise
+	ready  chan struct{} // closed once the process is considered ready
+	done   chan struct{} // closed once the process has exited
+	output sync.WaitGroup
+
+	stdin       io.WriteCloser // process stdin (the PTY master in PTY mode)
+	stdinMu     sync.Mutex
+	stdinClosed bool // EOF was sent by closing the stdin pipe
+
+	stopSignal  syscall.Signal // signal sent first when stopping
+	stopTimeout time.Duration  // grace period before escalating to SIGKILL
+
+	readyTimeout time.Duration  // how long dependents wait for ready
+	logMatch     *regexp.Regexp // ready check regex for output lines
+	logMatched   chan struct{}  // closed once an output line matched logMatch
+	logOnce      sync.Once
+}
+
+// ProcessStatus represents the status of a process sent to the event sink
+type ProcessStatus struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	ExitCode *int   `json:"exit_code"`
+	Restarts int    `json:"restarts"`      // automatic restarts since the last manual start
+	PID      int    `json:"pid,omitempty"` // set when the process starts
+}
+
+// ProcessOutput represents a line of output from a process
+type ProcessOutput struct {
+	Seq      uint64    `json:"seq"`  // increases across all processes
+	Time     time.Time `json:"time"` // when the line was captured
+	Name     string    `json:"name"`
+	Line     string    `json:"line"`
+	IsStderr bool      `json:"is_stderr"`
+}
+
+// ProcessInfo represents basic process info for the event sink
+type ProcessInfo struct {
+	Name     string `json:"name"` // instance name, e.g. "web" or "web.2" when scaled
+	Type     string `json:"type"` // process type from the Procfile, e.g. "web"
+	Disabled bool   `json:"disabled"`
+}
+
+// ProcfileLoaded represents the event when a procfile is loaded
+type ProcfileLoaded struct {
+	Path      string        `json:"path"`
+	Processes []ProcessInfo `json:"processes"`
+	EnvLoaded bool          `json:"env_loaded"`
+	EnvCount  int           `json:"env_count"`
+	EnvFiles  []string      `json:"env_files"` // loaded env files, lowest precedence first
+}
+
+// spawnProcess starts a process instance and monitors it
+func (s *Supervisor) spawnProcess(name string, def ProcessDefinition) error {
+	s.mu.Lock()
+	// Check if already running
+	if _, exists := s.running[name]; exists {
+		s.mu.Unlock()
+		return nil // Already running, not an error
+	}
+	// Build environment: system env < env files < per-process vars < PORT/PS
+	// and the session tag
+	env := buildEnv(s.instanceEnvLayers(def, instanceNumber(name, def.Name)))
+	s.mu.Unlock()
+
+	// Create cancellable context
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Determine shell based on OS
+	var shell, shellArg string
+	if runtime.GOOS == "windows" {
+		shell = "cmd"
+		shellArg = "/C"
+	} else {
+		shell = "sh"
+		shellArg = "-c"
+	}
+
+	// Expand variables ourselves so commands read the same under any shell
+	command := def.Command
+	if def.Expand {
+		expanded, err := expandCommand(command, envLookup(env))
+		if err != nil {
+			cancel()
+			return fmt.Errorf("expanding command: %w", err)
+		}
+		command = expanded
+	}
+
+	cmd := exec.CommandContext(ctx, shell, shellArg, command)
+	cmd.Env = env
+
+	// Set working directory to procfile's parent directory
+	if s.procfilePath != "" {
+		cmd.Dir = getParentDir(s.procfilePath)
+	}
+
+	// Set up process group for clean killing on Unix
+	if runtime.GOOS != "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	}
+
+	var stdout, stderr io.ReadCloser
+	var stdin io.WriteCloser
+	var ptyMaster *os.File
+	if def.PTY {
+		// Run on a pseudo-terminal; stdout and stderr share it
+		master, slave, err := openPTY()
+		if err != nil {
+			cancel()
+			return err
+		}
+		s.mu.Lock()
+		cols, rows := s.termCols, s.termRows
+		s.mu.Unlock()
+		setPTYSize(master, cols, rows)
+
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = slave, slave, slave
+		// New session with the terminal as controlling tty (also a new process group)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+		if !hasEnvKey(cmd.Env, "TERM") {
+			cmd.Env = append(cmd.Env, "TERM=xterm-256color")
+		}
+
+		if err := cmd.Start(); err != nil {
+			master.Close()
+			slave.Close()
+			cancel()
+			return err
+		}
+		// The child holds its own copy of the slave end
+		slave.Close()
+
+		ptyMaster = master
+		stdin, stdout = master, master
+	} else {
+		// Get stdin, stdout and stderr pipes
+		var err error
+		stdin, err = cmd.StdinPipe()
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		// Output pipes are our own rather than cmd.StdoutPipe, which Wait
+		// closes as soon as the process exits, cutting off unread output
+		stdoutR, stdoutW, err := os.Pipe()
+		if err != nil {
+			cancel()
+			return err
+		}
+		stderrR, stderrW, err := os.Pipe()
+		if err != nil {
+			stdoutR.Close()
+			stdoutW.Close()
+			cancel()
+			return err
+		}
+		cmd.Stdout, cmd.Stderr = stdoutW, stderrW
+
+		// Start the process
+		err = cmd.Start()
+		// The child holds its own copies of the write ends
+		stdoutW.Close()
+		stderrW.Close()
+		if err != nil {
+			stdoutR.Close()
+			stderrR.Close()
+			cancel()
+			return err
+		}
+
+		stdout, stderr = stdoutR, stderrR
+	}
+
+	// Get process group ID
+	pgid := 0
+	if runtime.GOOS != "windows" && cmd.Process != nil {
+		pgid, _ = syscall.Getpgid(cmd.Process.Pid)
+	}
+
+	// Store the process handle
+	handle := &ProcessHandle{
+		cmd:    cmd,
+		cancel: cancel,
+		pgid:   pgid,
+		pty:    ptyMaster,
+		stdin:  stdin,
+		ready:  make(chan struct{}),
+		done:   make(chan struct{}),
+
+		stopSignal:  syscall.SIGTERM,
+		stopTimeout: defaultStopTimeout,
+	}
+	if def.StopSignal != "" {
+		// Already validated when the options were loaded
+		handle.stopSignal, _ = parseStopSignal(def.StopSignal)
+	}
+	if def.StopTimeout > 0 {
+		handle.stopTimeout = time.Duration(def.StopTimeout) * time.Second
+	}
+	if def.Ready != nil {
+		handle.readyTimeout = def.Ready.timeout()
+		if def.Ready.Log != "" {
+			// Already validated when the options were loaded
+			handle.logMatch = regexp.MustCompile(def.Ready.Log)
+			handle.logMatched = make(chan struct{})
+		}
+	}
+	s.mu.Lock()
+	s.running[name] = handle
+	restarts := s.restartCount(name)
+	s.mu.Unlock()
+
+	if def.Ready != nil {
+		// Emit starting status, the readiness watcher reports ready/unhealthy
+		s.report(ProcessStatus{
+			Name:     name,
+			Status:   "starting",
+			ExitCode: nil,
+			Restarts: restarts,
+			PID:      cmd.Process.Pid,
+		})
+		go s.watchReadiness(name, handle, def.Ready)
+	} else {
+		// Without a ready check a started process counts as ready
+		close(handle.ready)
+		s.report(ProcessStatus{
+			Name:     name,
+			Status:   "running",
+			ExitCode: nil,
+			Restarts: restarts,
+			PID:      cmd.Process.Pid,
+		})
+	}
+
+	// Read stdout and stderr in goroutines
+	handle.output.Add(1)
+	go s.readOutput(name, handle, stdout, false)
+	if stderr != nil {
+		handle.output.Add(1)
+		go s.readOutput(name, handle, stderr, true)
+	}
+
+	// Monitor process in goroutine. It counts as live until any automatic
+	// restart has been handed over to the next instance.
+	s.live.Add(1)
+	go func() {
+		defer s.live.Add(-1)
+
+		// Wait for process to exit
+		err := cmd.Wait()
+		close(handle.done)
+
+		// Let the last output lines through before reporting the exit
+		drained := make(chan struct{})
+		go func() {
+			handle.output.Wait()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(outputDrainTimeout):
+		}
+
+		// Get exit code
+		var exitCode *int
+		if err != nil {
+			if exitErr, ok := err.(*exec.ExitError); ok {
+				code := exitErr.ExitCode()
+				exitCode = &code
+			}
+		} else {
+			code := 0
+			exitCode = &code
+		}
+
+		// Check if process was manually stopped (removed from running map or
+		// replaced by a newer handle after a quick stop/start)
+		s.mu.Lock()
+		current, exists := s.running[name]
+		stillRunning := exists && current == handle
+		if stillRunning {
+			delete(s.running, name)
+		}
+		restarts := s.restartCount(name)
+		s.mu.Unlock()
+
+		// Only emit stopped status if process wasn't manually stopped
+		if stillRunning {
+			s.report(ProcessStatus{
+				Name:     name,
+				Status:   "stopped",
+				ExitCode: exitCode,
+				Restarts: restarts,
+			})
+
+			// Restart according to the process restart policy
+			s.scheduleRestart(name, exitCode)
+		}
+	}()
+
+	return nil
+}
+
+// Env returns the system environment (only its allowlist with clean_env)
+// with the env file vars over it, each key once
+func (s *Supervisor) Env() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return buildEnv(append([]envLayer{systemEnvLayer(s.cleanEnv, s.envAllowlist)}, s.envLayers...))
+}
+
+// EffectiveEnv returns the environment a process type (its first instance)
+// or instance starts with, sorted by key. Every variable tells where its
+// value came from: the system environment, an env file, the process's
+// options or the supervisor (PORT, PS and the session tag), in that order
+// of precedence.
+func (s *Supervisor) EffectiveEnv(name string) ([]EnvVar, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def, instances, exists := s.resolveProcess(name)
+	if !exists {
+		return nil, fmt.Errorf("unknown process %q", name)
+	}
+	instance := instanceNumber(instances[0], def.Name)
+	return envVarsOf(s.instanceEnvLayers(def, instance)), nil
+}
+
+// ProcessEnv returns the KEY=value environment a process type (its first
+// instance) or instance starts with, like EffectiveEnv without the sources
+func (s *Supervisor) ProcessEnv(name string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	def, instances, exists := s.resolveProcess(name)
+	if !exists {
+		return nil, fmt.Errorf("unknown process %q", name)
+	}
+	instance := instanceNumber(instances[0], def.Name)
+	return buildEnv(s.instanceEnvLayers(def, instance)), nil
+}
+
+// EnvFiles returns the loaded global env files, lowest precedence first
+func (s *Supervisor) EnvFiles() []EnvFile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files := make([]EnvFile, 0, len(s.envLayers))
+	for _, layer := range s.envLayers {
+		files = append(files, EnvFile{Path: layer.source, Count: len(layer.vars)})
+	}
+	return files
+}
+
+// readOutput emits each line read from a process output stream. Read errors
+// are reported into the log instead of silently ending the output.
+func (s *Supervisor) readOutput(name string, handle *ProcessHandle, r io.ReadCloser, isStderr bool) {
+	err := readLines(r, maxLineLength, func(line string) {
+		handle.matchReadyLog(line)
+		s.emitOutput(name, line, isStderr)
+	})
+
+	// A terminal reports EIO after hanging up, which just means the output
+	// is over
+	if err != nil && !errors.Is(err, syscall.EIO) {
+		s.emitOutput(name, fmt.Sprintf("Error reading output: %v", err), true)
+	}
+
+	// The pipe or PTY master is ours to close once the output is over
+	r.Close()
+	handle.output.Done()
+}
+
+// stopProcess stops a running process
+func (s *Supervisor) stopProcess(name string) error {
+	// Cancel a pending auto-restart so the process stays stopped
+	restartPending := s.resetRestarts(name)
+
+	s.mu.Lock()
+	handle, exists := s.running[name]
+	if !exists {
+		s.mu.Unlock()
+		if restartPending {
+			s.report(ProcessStatus{
+				Name:     name,
+				Status:   "stopped",
+				ExitCode: nil,
+			})
+		}
+		return nil // Not running, not an error
+	}
+	delete(s.running, name)
+	restarts := s.restartCount(name)
+	s.mu.Unlock()
+
+	// Emit stopping status while the process shuts down
+	s.report(ProcessStatus{
+		Name:     name,
+		Status:   "stopping",
+		ExitCode: nil,
+		Restarts: restarts,
+	})
+
+	// On Unix, signal the entire process group and wait for a graceful exit
+	if runtime.GOOS != "windows" && handle.pgid > 0 {
+		syscall.Kill(-handle.pgid, handle.stopSignal)
+
+		timer := time.NewTimer(handle.stopTimeout)
+		select {
+		case <-handle.done:
+		case <-s.killNow:
+		case <-timer.C:
+			s.emitOutput(name, fmt.Sprintf("Did not exit within %s after %s, sending SIGKILL", handle.stopTimeout, SignalName(handle.stopSignal)), true)
+		}
+		timer.Stop()
+
+		// Kill whatever is left in the process group
+		syscall.Kill(-handle.pgid, syscall.SIGKILL)
+	}
+
+	// Cancel the context (kills the process if it is still around)
+	handle.cancel()
+	<-handle.done
+
+	// Emit stopped status
+	s.report(ProcessStatus{
+		Name:     name,
+		Status:   "stopped",
+		ExitCode: nil,
+		Restarts: restarts,
+	})
+
+	return nil
+}
+
+// ForceStop makes running and future stops skip the grace period and kill
+// process groups right away
+func (s *Supervisor) ForceStop() {
+	s.killOnce.Do(func() { close(s.killNow) })
+}
+
+// KillOrphans finds and kills any processes from previous sessions
+// that have the PROCFILE_RUNNER_SESSION environment variable set
+func (s *Supervisor) KillOrphans() {
+	if runtime.GOOS == "windows" {
+		return // Not implemented for Windows
+	}
+
+	s.mu.Lock()
+	currentSession := s.sessionID
+	s.mu.Unlock()
+
+	// Use pgrep to find processes with our env var
+	// This finds processes where the env contains PROCFILE_RUNNER_SESSION
+	cmd := exec.Command("sh", "-c",
+		fmt.Sprintf("ps -eo pid,command | grep -v grep | while read pid cmd; do "+
+			"if [ -f /proc/$pid/environ ] 2>/dev/null; then "+
+			"if grep -q '%s=' /proc/$pid/environ 2>/dev/null; then "+
+			"session=$(cat /proc/$pid/environ 2>/dev/null | tr '\\0' '\\n' | grep '%s=' | cut -d= -f2); "+
+			"if [ -n \"$session\" ] && [ \"$session\" != \"%s\" ]; then echo $pid; fi; "+
+			"fi; fi; done",
+			ProcessRunnerEnvKey, ProcessRunnerEnvKey, currentSession))
+
+	output, err := cmd.Output()
+	if err != nil {
+		// On macOS, /proc doesn't exist, try alternative method using ps and lsof
+		s.killOrphanedProcessesMacOS()
+		return
+	}
+
+	// Kill each orphaned process
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	for _, line := range lines {
+		if pid, err := strconv.Atoi(strings.TrimSpace(line)); err == nil && pid > 0 {
+			syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+}
+
+// killOrphanedProcessesMacOS uses a different approach for macOS
+func (s *Supervisor) killOrphanedProcessesMacOS() {
+	// On macOS, we'll track PIDs in a file and clean them up
+	// For now, use pkill with a pattern match on the command
+	// This is a simpler approach that kills any sh -c processes started by us
+
+	s.mu.Lock()
+	currentSession := s.sessionID
+	s.mu.Unlock()
+
+	// Read the session file to find old session PIDs
+	sessionFile := getSessionFilePath()
+	data, err := os.ReadFile(sessionFile)
+	if err != nil {
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, line := range lines {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		session := parts[0]
+		pidStr := parts[1]
+
+		// Skip current session
+		if session == currentSession {
+			continue
+		}
+
+		// Kill the process group
+		if pgid, err := strconv.Atoi(pidStr); err == nil && pgid > 0 {
+			syscall.Kill(-pgid, syscall.SIGKILL)
+		}
+	}
+
+	// Clear the file and write current session
+	os.Remove(sessionFile)
+}
+
+// trackProcessGroup records a process group for orphan cleanup
+func (s *Supervisor) trackProcessGroup(pgid int) {
+	if pgid <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	sessionID := s.sessionID
+	s.mu.Unlock()
+
+	sessionFile := getSessionFilePath()
+
+	// Append to session file
+	f, err := os.OpenFile(sessionFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "%s:%d\n", sessionID, pgid)
+}
+
+// hasEnvKey reports whether env contains a KEY=value entry for key
+func hasEnvKey(env []string, key string) bool {
+	for _, entry := range env {
+		if strings.HasPrefix(entry, key+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// getSessionFilePath returns the path to the session tracking file
+func getSessionFilePath() string {
+	home, _ := os.UserHomeDir()
+	return home + "/.config/procfile-runner/sessions.txt"
+}
+
+// getParentDir returns the parent directory of a file path
+func getParentDir(path string) string {
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i] == '/' || path[i] == '\\' {
+			return path[:i]
+		}
+	}
+	return "."
+}
@@ -1,14 +1,22 @@
-package main
+package supervisor
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
+// ErrNoProcfile is returned for a directory without a Procfile
+var ErrNoProcfile = errors.New("no Procfile")
+
 // ProcessDefinition represents a single process from a Procfile
 type ProcessDefinition struct {
 	Name     string `json:"name"`
 	Command  string `json:"command"`
 	Disabled bool   `json:"disabled"`
+	ProcessOptions
 }
 
 // ParseProcfile parses a Procfile content and returns process definitions
@@ -82,3 +90,21 @@ func ParseProcfile(content string) []ProcessDefinition {
 
 	return definitions
 }
+
+// ResolveProcfile returns the Procfile path for path: path itself for a
+// file, the Procfile in it for a directory
+func ResolveProcfile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return path, nil
+	}
+
+	procfile := filepath.Join(path, "Procfile")
+	if _, err := os.Stat(procfile); err != nil {
+		return "", fmt.Errorf("%w in %s", ErrNoProcfile, path)
+	}
+	return procfile, nil
+}
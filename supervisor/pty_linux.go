@@ -0,0 +1,67 @@
+//go:build linux
+
+package supervisor
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// openPTY allocates a pseudo-terminal and returns its master and slave ends
+func openPTY() (*os.File, *os.File, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Unlock the slave and look up its number
+	var unlock int32
+	if err := ioctl(master, syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("unlock pty: %w", err)
+	}
+	var ptyNumber uint32
+	if err := ioctl(master, syscall.TIOCGPTN, uintptr(unsafe.Pointer(&ptyNumber))); err != nil {
+		master.Close()
+		return nil, nil, fmt.Errorf("get pty number: %w", err)
+	}
+
+	slave, err := os.OpenFile(fmt.Sprintf("/dev/pts/%d", ptyNumber), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, err
+	}
+
+	return master, slave, nil
+}
+
+// setPTYSize sets the window size of a pseudo-terminal; the kernel sends
+// SIGWINCH to the foreground process group
+func setPTYSize(master *os.File, cols int, rows int) error {
+	size := struct {
+		rows, cols, x, y uint16
+	}{uint16(rows), uint16(cols), 0, 0}
+	return ioctl(master, syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&size)))
+}
+
+// ioctl runs an ioctl on f without switching it to blocking mode, so a
+// pending Read is still interrupted by Close
+func ioctl(f *os.File, request uintptr, arg uintptr) error {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var errno syscall.Errno
+	if err := conn.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg)
+	}); err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
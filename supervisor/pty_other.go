@@ -0,0 +1,18 @@
+//go:build !linux
+
+package supervisor
+
+import (
+	"fmt"
+	"os"
+)
+
+// openPTY is only implemented on Linux
+func openPTY() (*os.File, *os.File, error) {
+	return nil, nil, fmt.Errorf("PTY mode is only supported on Linux")
+}
+
+// setPTYSize is only implemented on Linux
+func setPTYSize(master *os.File, cols int, rows int) error {
+	return fmt.Errorf("PTY mode is only supported on Linux")
+}
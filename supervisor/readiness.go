@@ -0,0 +1,140 @@
+package supervisor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaultReadyTimeout = 30 * time.Second
+	readyPollInterval   = 500 * time.Millisecond
+)
+
+// ReadyCheck describes how to tell that a started process is actually ready.
+// All configured checks must pass.
+type ReadyCheck struct {
+	TCP     string `json:"tcp,omitempty"`     // "3000" or "host:port" that must accept connections
+	HTTP    string `json:"http,omitempty"`    // URL that must return a 2xx response
+	Log     string `json:"log,omitempty"`     // regex matched against stdout/stderr lines
+	Timeout int    `json:"timeout,omitempty"` // seconds before the process is marked unhealthy (default 30)
+}
+
+// timeout returns the configured readiness timeout
+func (c *ReadyCheck) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return time.Duration(c.Timeout) * time.Second
+	}
+	return defaultReadyTimeout
+}
+
+// tcpAddress returns the dial address, defaulting the host to localhost
+func (c *ReadyCheck) tcpAddress() string {
+	if strings.Contains(c.TCP, ":") {
+		return c.TCP
+	}
+	return "127.0.0.1:" + c.TCP
+}
+
+// probeTCP reports whether something accepts connections on addr
+func probeTCP(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// probeHTTP reports whether url answers with a 2xx status
+func probeHTTP(url string) bool {
+	client := http.Client{Timeout: 2 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// checkReady runs all configured checks once
+func (h *ProcessHandle) checkReady(check *ReadyCheck) bool {
+	if h.logMatch != nil {
+		select {
+		case <-h.logMatched:
+		default:
+			return false
+		}
+	}
+	if check.TCP != "" && !probeTCP(check.tcpAddress()) {
+		return false
+	}
+	if check.HTTP != "" && !probeHTTP(check.HTTP) {
+		return false
+	}
+	return true
+}
+
+// matchReadyLog marks the log check as passed when a line matches its regex
+func (h *ProcessHandle) matchReadyLog(line string) {
+	if h.logMatch != nil && h.logMatch.MatchString(line) {
+		h.logOnce.Do(func() { close(h.logMatched) })
+	}
+}
+
+// watchReadiness polls the ready check of a started process and emits
+// "ready" once it passes, or "unhealthy" when the timeout expires first.
+// Polling continues after a timeout so a slow process can still turn ready.
+func (s *Supervisor) watchReadiness(name string, handle *ProcessHandle, check *ReadyCheck) {
+	timeout := time.NewTimer(check.timeout())
+	defer timeout.Stop()
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	timeoutC := timeout.C
+	logMatched := handle.logMatched
+
+	for {
+		if handle.checkReady(check) {
+			close(handle.ready)
+			s.emitHandleStatus(name, handle, "ready")
+			return
+		}
+
+		select {
+		case <-handle.done:
+			return
+		case <-timeoutC:
+			timeoutC = nil
+			if s.emitHandleStatus(name, handle, "unhealthy") {
+				s.emitOutput(name, fmt.Sprintf("Readiness check did not pass within %s", check.timeout()), true)
+			}
+		case <-logMatched:
+			logMatched = nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// emitHandleStatus emits a status event only if handle is still the running
+// instance of the process, so a stopped process doesn't turn green again
+func (s *Supervisor) emitHandleStatus(name string, handle *ProcessHandle, status string) bool {
+	s.mu.Lock()
+	current, exists := s.running[name]
+	restarts := s.restartCount(name)
+	s.mu.Unlock()
+
+	if !exists || current != handle {
+		return false
+	}
+
+	s.report(ProcessStatus{
+		Name:     name,
+		Status:   status,
+		ExitCode: nil,
+		Restarts: restarts,
+	})
+	return true
+}
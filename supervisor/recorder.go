@@ -0,0 +1,173 @@
+package supervisor
+
+import (
+	"sync"
+	"time"
+)
+
+// Recorder is an EventSink that keeps every event in memory, for tests and
+// front-ends that poll
+type Recorder struct {
+	mu       sync.Mutex
+	statuses []ProcessStatus
+	outputs  []ProcessOutput
+	loaded   []ProcfileLoaded
+	scaled   []ProcessScaled
+	reloaded []ProcfileReloaded
+	changed  chan struct{} // closed and replaced on every event
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{changed: make(chan struct{})}
+}
+
+// OnStatus records a status change
+func (r *Recorder) OnStatus(status ProcessStatus) {
+	r.record(func() { r.statuses = append(r.statuses, status) })
+}
+
+// OnOutput records a line of output
+func (r *Recorder) OnOutput(output ProcessOutput) {
+	r.record(func() { r.outputs = append(r.outputs, output) })
+}
+
+// OnProcfileLoaded records a loaded Procfile
+func (r *Recorder) OnProcfileLoaded(loaded ProcfileLoaded) {
+	r.record(func() { r.loaded = append(r.loaded, loaded) })
+}
+
+// OnScaled records a scale change
+func (r *Recorder) OnScaled(scaled ProcessScaled) {
+	r.record(func() { r.scaled = append(r.scaled, scaled) })
+}
+
+// OnProcfileReloaded records a reload
+func (r *Recorder) OnProcfileReloaded(reloaded ProcfileReloaded) {
+	r.record(func() { r.reloaded = append(r.reloaded, reloaded) })
+}
+
+// record applies an update and wakes up waiters
+func (r *Recorder) record(update func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	update()
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Statuses returns the recorded status changes of a process instance, or of
+// all processes when name is empty
+func (r *Recorder) Statuses(name string) []ProcessStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var statuses []ProcessStatus
+	for _, status := range r.statuses {
+		if name == "" || status.Name == name {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// Lines returns the recorded output lines of a process instance, or of all
+// processes when name is empty
+func (r *Recorder) Lines(name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var lines []string
+	for _, output := range r.outputs {
+		if name == "" || output.Name == name {
+			lines = append(lines, output.Line)
+		}
+	}
+	return lines
+}
+
+// Loaded returns the recorded Procfile loads
+func (r *Recorder) Loaded() []ProcfileLoaded {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ProcfileLoaded(nil), r.loaded...)
+}
+
+// Scaled returns the recorded scale changes
+func (r *Recorder) Scaled() []ProcessScaled {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ProcessScaled(nil), r.scaled...)
+}
+
+// Reloaded returns the recorded reloads
+func (r *Recorder) Reloaded() []ProcfileReloaded {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ProcfileReloaded(nil), r.reloaded...)
+}
+
+// WaitReloaded waits until n reloads have been recorded and returns the
+// last of them
+func (r *Recorder) WaitReloaded(n int, timeout time.Duration) (ProcfileReloaded, bool) {
+	var found ProcfileReloaded
+	ok := r.wait(timeout, func() bool {
+		if len(r.reloaded) < n {
+			return false
+		}
+		found = r.reloaded[len(r.reloaded)-1]
+		return true
+	})
+	return found, ok
+}
+
+// WaitStatus waits until a process instance has reported status n times in
+// total and returns the last of them
+func (r *Recorder) WaitStatus(name string, status string, n int, timeout time.Duration) (ProcessStatus, bool) {
+	var found ProcessStatus
+	ok := r.wait(timeout, func() bool {
+		count := 0
+		for _, s := range r.statuses {
+			if s.Name == name && s.Status == status {
+				found = s
+				count++
+			}
+		}
+		return count >= n
+	})
+	return found, ok
+}
+
+// WaitLine waits until a process instance has printed line
+func (r *Recorder) WaitLine(name string, line string, timeout time.Duration) bool {
+	return r.wait(timeout, func() bool {
+		for _, output := range r.outputs {
+			if output.Name == name && output.Line == line {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// wait calls done with r.mu held after every event until it returns true
+// or the timeout expires
+func (r *Recorder) wait(timeout time.Duration, done func() bool) bool {
+	deadline := time.After(timeout)
+	for {
+		r.mu.Lock()
+		if done() {
+			r.mu.Unlock()
+			return true
+		}
+		changed := r.changed
+		r.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline:
+			return false
+		}
+	}
+}
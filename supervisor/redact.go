@@ -0,0 +1,177 @@
+package supervisor
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// secretMask replaces secrets in process output
+	secretMask = "********"
+	// minSecretLength keeps short values like "1" or "dev" from being masked
+	// everywhere they happen to appear
+	minSecretLength = 4
+)
+
+// defaultSecretKeys match the env keys whose values are masked in output
+var defaultSecretKeys = []string{"*SECRET*", "*TOKEN*", "*PASSWORD*", "*PASSWD*", "*_KEY", "*CREDENTIALS*"}
+
+// RedactOptions configures the masking of secrets in process output
+//
+// Example Procfile.json:
+//
+//	{
+//	  "redact": {
+//	    "keys": ["*_SECRET", "*_TOKEN", "*PASSWORD*"],
+//	    "patterns": ["Bearer [A-Za-z0-9._-]+", "password=(\\S+)"]
+//	  }
+//	}
+type RedactOptions struct {
+	Disabled bool     `json:"disabled,omitempty"` // show output as written
+	Keys     []string `json:"keys,omitempty"`     // env key globs whose values are masked (replaces the defaults)
+	Patterns []string `json:"patterns,omitempty"` // regular expressions masked in every line; with groups only the groups are masked
+}
+
+// redactor masks secrets in lines of process output
+type redactor struct {
+	values   *strings.Replacer // secret env values
+	patterns []*regexp.Regexp
+}
+
+// newRedactor compiles the redact options against the environment layers
+// the processes get. Values of keys matching the key globs
+// (case-insensitive) are masked wherever they appear, each line of a
+// multiline value on its own. It returns nil when nothing is masked.
+func newRedactor(opts *RedactOptions, layers []envLayer) (*redactor, error) {
+	if opts == nil {
+		opts = &RedactOptions{}
+	}
+	if opts.Disabled {
+		return nil, nil
+	}
+
+	keys := opts.Keys
+	if len(keys) == 0 {
+		keys = defaultSecretKeys
+	}
+	for _, key := range keys {
+		if _, err := path.Match(strings.ToUpper(key), ""); err != nil {
+			return nil, fmt.Errorf("redact: invalid key pattern %q", key)
+		}
+	}
+
+	r := &redactor{}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("redact: invalid pattern %q: %w", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	// Output is masked line by line, so multiline values like PEM keys are
+	// registered per line
+	seen := make(map[string]bool)
+	var secrets []string
+	for _, layer := range layers {
+		for key, value := range layer.vars {
+			if !secretKey(key, keys) {
+				continue
+			}
+			for _, line := range strings.Split(value, "\n") {
+				line = strings.TrimSuffix(line, "\r")
+				if len(line) < minSecretLength || seen[line] {
+					continue
+				}
+				seen[line] = true
+				secrets = append(secrets, line)
+			}
+		}
+	}
+	if len(secrets) > 0 {
+		// Longer values first, so a secret containing another is masked whole
+		sort.Slice(secrets, func(i, j int) bool {
+			if len(secrets[i]) != len(secrets[j]) {
+				return len(secrets[i]) > len(secrets[j])
+			}
+			return secrets[i] < secrets[j]
+		})
+		pairs := make([]string, 0, 2*len(secrets))
+		for _, secret := range secrets {
+			pairs = append(pairs, secret, secretMask)
+		}
+		r.values = strings.NewReplacer(pairs...)
+	}
+
+	if r.values == nil && len(r.patterns) == 0 {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// secretKey reports whether an env key matches one of the key globs
+func secretKey(key string, globs []string) bool {
+	key = strings.ToUpper(key)
+	for _, glob := range globs {
+		if matched, _ := path.Match(strings.ToUpper(glob), key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// redact masks the secrets in line. A nil redactor leaves it as is.
+func (r *redactor) redact(line string) string {
+	if r == nil {
+		return line
+	}
+	if r.values != nil {
+		line = r.values.Replace(line)
+	}
+	for _, re := range r.patterns {
+		line = maskMatches(re, line)
+	}
+	return line
+}
+
+// maskMatches masks the matches of re in line, or only their groups when re
+// has any
+func maskMatches(re *regexp.Regexp, line string) string {
+	var spans [][2]int
+	for _, match := range re.FindAllStringSubmatchIndex(line, -1) {
+		if len(match) == 2 {
+			spans = append(spans, [2]int{match[0], match[1]})
+			continue
+		}
+		for i := 2; i < len(match); i += 2 {
+			spans = append(spans, [2]int{match[i], match[i+1]})
+		}
+	}
+	if spans == nil {
+		return line
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, span := range spans {
+		// Skip groups that didn't take part, are empty or lie inside an
+		// earlier one
+		if span[0] < last || span[1] <= span[0] {
+			continue
+		}
+		sb.WriteString(line[last:span[0]])
+		sb.WriteString(secretMask)
+		last = span[1]
+	}
+	sb.WriteString(line[last:])
+	return sb.String()
+}
+
+// Redact masks the secrets of the loaded project's environment and the
+// configured patterns in text, like process output is masked
+func (s *Supervisor) Redact(text string) string {
+	return s.redactor.Load().redact(text)
+}
@@ -0,0 +1,233 @@
+package supervisor
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ProcfileReloaded is the event when the Procfile, its side config or its
+// env files changed on disk and were loaded again. Commented-out processes
+// count as absent: uncommenting one adds it.
+type ProcfileReloaded struct {
+	Path       string        `json:"path"`
+	Processes  []ProcessInfo `json:"processes"`
+	Added      []string      `json:"added"`       // process types
+	Removed    []string      `json:"removed"`     // process types
+	Changed    []string      `json:"changed"`     // types whose command, options, scale, PORT or own env changed
+	EnvChanged []string      `json:"env_changed"` // env file keys added, removed or changed
+	Restarted  []string      `json:"restarted"`   // running types restarted for the changes
+	Stopped    []string      `json:"stopped"`     // running types stopped as they were removed
+	Error      string        `json:"error,omitempty"`
+}
+
+// Summary describes a reload in one line, like "Procfile reloaded: added
+// worker; changed web; .env changed PORT; restarted web"
+func (r ProcfileReloaded) Summary() string {
+	if r.Error != "" {
+		return fmt.Sprintf("Reloading %s failed: %s", filepath.Base(r.Path), r.Error)
+	}
+
+	var parts []string
+	for _, part := range []struct {
+		label string
+		names []string
+	}{
+		{"added", r.Added},
+		{"removed", r.Removed},
+		{"changed", r.Changed},
+		{".env changed", r.EnvChanged},
+		{"restarted", r.Restarted},
+		{"stopped", r.Stopped},
+	} {
+		if len(part.names) > 0 {
+			parts = append(parts, part.label+" "+strings.Join(part.names, ", "))
+		}
+	}
+	if len(parts) == 0 {
+		parts = append(parts, "no changes")
+	}
+	return fmt.Sprintf("%s reloaded: %s", filepath.Base(r.Path), strings.Join(parts, "; "))
+}
+
+// reloadState is the auto-reload configuration and its file watcher
+type reloadState struct {
+	enabled        bool
+	restartChanged bool
+	watcher        *fileWatcher
+}
+
+// SetAutoReload makes the supervisor load the Procfile again whenever it,
+// its side config or its env files change on disk. With restartChanged,
+// running processes whose command or environment changed are restarted and
+// removed ones stopped; otherwise running processes are left alone.
+func (s *Supervisor) SetAutoReload(enabled bool, restartChanged bool) {
+	s.reloadMu.Lock()
+	s.reload.enabled = enabled
+	s.reload.restartChanged = restartChanged
+	s.reloadMu.Unlock()
+
+	s.watchProcfile()
+}
+
+// watchProcfile (re)starts watching the files of the loaded Procfile, or
+// stops watching when auto-reload is off
+func (s *Supervisor) watchProcfile() {
+	path := s.ProcfilePath()
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if s.reload.watcher != nil {
+		s.reload.watcher.close()
+		s.reload.watcher = nil
+	}
+	if !s.reload.enabled || path == "" {
+		return
+	}
+
+	s.mu.Lock()
+	paths := append([]string{path, path + ".json"}, s.envPaths...)
+	s.mu.Unlock()
+	watcher, err := watchFiles(paths, s.reloadFromDisk)
+	if err != nil {
+		s.sink.OnProcfileReloaded(ProcfileReloaded{Path: path, Error: "auto-reload disabled: " + err.Error()})
+		return
+	}
+	s.reload.watcher = watcher
+}
+
+// reloadFromDisk loads the current Procfile again and reports what changed.
+// When the files don't load, the previous Procfile stays in place.
+func (s *Supervisor) reloadFromDisk() {
+	s.mu.Lock()
+	path := s.procfilePath
+	oldProcesses := s.processes
+	oldFormation := s.formation
+	oldPorts := s.ports
+	oldEnv := s.envVars
+	oldProcessEnv := s.processEnv
+	s.mu.Unlock()
+
+	loaded, err := s.load(path)
+	if err != nil {
+		s.sink.OnProcfileReloaded(ProcfileReloaded{Path: path, Error: err.Error()})
+		return
+	}
+
+	// Like Load: drop restarts of the old definitions and watch the files
+	// of the new one
+	for _, name := range s.activate() {
+		s.report(ProcessStatus{Name: name, Status: "stopped", ExitCode: nil})
+	}
+
+	s.mu.Lock()
+	reloaded := ProcfileReloaded{
+		Path:       path,
+		Processes:  loaded.Processes,
+		EnvChanged: changedKeys(oldEnv, s.envVars),
+	}
+	for _, name := range s.order {
+		def := s.processes[name]
+		old, existed := oldProcesses[name]
+		switch {
+		case def.Disabled:
+		case !existed || old.Disabled:
+			reloaded.Added = append(reloaded.Added, name)
+		case !reflect.DeepEqual(old, def) || oldFormation[name] != s.formation[name] || oldPorts[name] != s.ports[name] ||
+			changedKeys(mergeEnvLayers(oldProcessEnv[name]), mergeEnvLayers(s.processEnv[name])) != nil:
+			reloaded.Changed = append(reloaded.Changed, name)
+		}
+	}
+	for name, old := range oldProcesses {
+		if def, exists := s.processes[name]; !old.Disabled && (!exists || def.Disabled) {
+			reloaded.Removed = append(reloaded.Removed, name)
+		}
+	}
+	sort.Strings(reloaded.Removed)
+	s.mu.Unlock()
+
+	s.reloadMu.Lock()
+	restartChanged := s.reload.restartChanged
+	s.reloadMu.Unlock()
+
+	if restartChanged {
+		s.restartChanged(&reloaded, oldFormation)
+	}
+
+	s.sink.OnProcfileReloaded(reloaded)
+}
+
+// restartChanged restarts the running process types affected by a reload
+// (all of them when .env changed) and stops the removed ones
+func (s *Supervisor) restartChanged(reloaded *ProcfileReloaded, oldFormation map[string]int) {
+	affected := reloaded.Changed
+	if len(reloaded.EnvChanged) > 0 {
+		affected = nil
+		for _, def := range s.Definitions() {
+			if !def.Disabled {
+				affected = append(affected, def.Name)
+			}
+		}
+	}
+
+	for _, name := range reloaded.Removed {
+		if running := s.runningInstances(name, oldFormation[name]); len(running) > 0 {
+			for _, instance := range running {
+				s.stopProcess(instance)
+			}
+			reloaded.Stopped = append(reloaded.Stopped, name)
+		}
+	}
+
+	for _, name := range affected {
+		running := s.runningInstances(name, oldFormation[name])
+		if len(running) == 0 {
+			continue
+		}
+		// Instances renamed by a scale change (web <-> web.1) stop under
+		// their old name; Restart starts the current ones
+		for _, instance := range running {
+			s.stopProcess(instance)
+		}
+		if err := s.Restart(name); err != nil {
+			s.emitOutput(name, "Restart after reload failed: "+err.Error(), true)
+		}
+		reloaded.Restarted = append(reloaded.Restarted, name)
+	}
+}
+
+// runningInstances returns the running instances of a process type as it
+// was scaled before a reload
+func (s *Supervisor) runningInstances(name string, count int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var running []string
+	for _, instance := range instanceNames(name, count) {
+		if _, ok := s.running[instance]; ok {
+			running = append(running, instance)
+		}
+	}
+	return running
+}
+
+// changedKeys returns the sorted keys added, removed or changed between two
+// environments
+func changedKeys(old map[string]string, current map[string]string) []string {
+	var keys []string
+	for key, value := range current {
+		if previous, ok := old[key]; !ok || previous != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range old {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
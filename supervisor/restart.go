@@ -0,0 +1,236 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Restart policies
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+const (
+	defaultRestartDelay    = 2 * time.Second
+	defaultRestartMaxDelay = 30 * time.Second
+	defaultMaxRestarts     = 5
+	defaultRestartWindow   = 60 * time.Second
+)
+
+// restartState tracks automatic restarts of a process since it was last
+// started by hand
+type restartState struct {
+	count   int                // automatic restarts so far
+	history []time.Time        // restart times within the crash-loop window
+	cancel  context.CancelFunc // cancels a pending backoff sleep
+}
+
+// validRestartPolicy reports whether policy is a known restart policy
+// (empty means "follow the global auto-restart toggle")
+func validRestartPolicy(policy string) bool {
+	switch policy {
+	case "", RestartNever, RestartOnFailure, RestartAlways:
+		return true
+	}
+	return false
+}
+
+// restartDelay returns the backoff before the given restart attempt (0-based),
+// doubling from restart_delay up to restart_max_delay
+func restartDelay(opts ProcessOptions, attempt int) time.Duration {
+	delay := defaultRestartDelay
+	if opts.RestartDelay > 0 {
+		delay = time.Duration(opts.RestartDelay) * time.Second
+	}
+	maxDelay := defaultRestartMaxDelay
+	if opts.RestartMaxDelay > 0 {
+		maxDelay = time.Duration(opts.RestartMaxDelay) * time.Second
+	}
+
+	for i := 0; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// restartPolicy returns the effective restart policy of a process. Must be
+// called with s.mu held.
+func (s *Supervisor) restartPolicy(def ProcessDefinition) string {
+	if def.Restart != "" {
+		return def.Restart
+	}
+	if s.globalAutoRestart {
+		return RestartOnFailure
+	}
+	return RestartNever
+}
+
+// shouldRestart reports whether a process that exited with exitCode should
+// be restarted under policy
+func shouldRestart(policy string, exitCode *int) bool {
+	switch policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitCode == nil || *exitCode != 0
+	}
+	return false
+}
+
+// scheduleRestart restarts an exited process according to its restart policy.
+// It sleeps for the backoff delay (cancellable via resetRestarts) and marks
+// the process as crashed once it restarts too often within the window. The
+// process restarts with its current definition, so a reloaded command takes
+// effect, and not at all once a reload removed or disabled it.
+func (s *Supervisor) scheduleRestart(name string, exitCode *int) {
+	s.mu.Lock()
+	def, exists := s.currentDefinition(name)
+	if !exists || !shouldRestart(s.restartPolicy(def), exitCode) {
+		s.mu.Unlock()
+		return
+	}
+
+	state := s.restarts[name]
+	if state == nil {
+		state = &restartState{}
+		s.restarts[name] = state
+	}
+
+	// Forget restarts that fell out of the crash-loop window
+	window := defaultRestartWindow
+	if def.RestartWindow > 0 {
+		window = time.Duration(def.RestartWindow) * time.Second
+	}
+	maxRestarts := defaultMaxRestarts
+	if def.MaxRestarts > 0 {
+		maxRestarts = def.MaxRestarts
+	}
+	now := time.Now()
+	recent := state.history[:0]
+	for _, t := range state.history {
+		if now.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	state.history = recent
+
+	if len(state.history) >= maxRestarts {
+		count := state.count
+		recentCount := len(state.history)
+		s.mu.Unlock()
+
+		s.emitOutput(name, fmt.Sprintf("Crashed: restarted %d times within %s, giving up", recentCount, window), true)
+		s.report(ProcessStatus{
+			Name:     name,
+			Status:   "crashed",
+			ExitCode: exitCode,
+			Restarts: count,
+		})
+		return
+	}
+
+	delay := restartDelay(def.ProcessOptions, len(state.history))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	state.cancel = cancel
+	state.history = append(state.history, now)
+	state.count++
+	count := state.count
+	s.mu.Unlock()
+
+	s.report(ProcessStatus{
+		Name:     name,
+		Status:   "restarting",
+		ExitCode: exitCode,
+		Restarts: count,
+	})
+	s.emitOutput(name, fmt.Sprintf("Auto-restarting process in %s (restart #%d)...", delay, count), false)
+
+	// Wait before restarting, unless the process is stopped or the Procfile reloaded
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return
+	}
+
+	s.mu.Lock()
+	if s.restarts[name] != state || ctx.Err() != nil {
+		s.mu.Unlock()
+		return
+	}
+	state.cancel = nil
+	// Double-check the policy still allows a restart: the global toggle or
+	// the restart options may have changed while waiting
+	def, exists = s.currentDefinition(name)
+	stillShouldRestart := exists && shouldRestart(s.restartPolicy(def), exitCode)
+	s.mu.Unlock()
+
+	if !stillShouldRestart {
+		s.report(ProcessStatus{
+			Name:     name,
+			Status:   "stopped",
+			ExitCode: exitCode,
+			Restarts: count,
+		})
+		return
+	}
+
+	s.spawnProcess(name, def)
+}
+
+// currentDefinition returns the definition of the process type an instance
+// belongs to, unless the type was removed or disabled. Must be called with
+// s.mu held.
+func (s *Supervisor) currentDefinition(name string) (ProcessDefinition, bool) {
+	def, _, exists := s.resolveProcess(name)
+	return def, exists && !def.Disabled
+}
+
+// resetRestarts forgets the restart history of a process and cancels a
+// pending restart. Returns true if a restart was pending.
+func (s *Supervisor) resetRestarts(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.restarts[name]
+	delete(s.restarts, name)
+	if state != nil && state.cancel != nil {
+		state.cancel()
+		return true
+	}
+	return false
+}
+
+// resetAllRestarts forgets all restart history and cancels pending restarts.
+// Returns the names of processes that had a restart pending.
+func (s *Supervisor) resetAllRestarts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pending []string
+	for name, state := range s.restarts {
+		if state.cancel != nil {
+			state.cancel()
+			pending = append(pending, name)
+		}
+	}
+	s.restarts = make(map[string]*restartState)
+	return pending
+}
+
+// restartCount returns the automatic restart count of a process. Must be
+// called with s.mu held.
+func (s *Supervisor) restartCount(name string) int {
+	if state := s.restarts[name]; state != nil {
+		return state.count
+	}
+	return 0
+}
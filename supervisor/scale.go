@@ -0,0 +1,228 @@
+package supervisor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultBasePort = 5000
+	maxScale        = 100
+)
+
+// ProcessScaled represents the event when a process type is scaled up or down
+type ProcessScaled struct {
+	Type      string        `json:"type"`
+	Instances []ProcessInfo `json:"instances"`
+}
+
+// ParseFormation parses a foreman-style formation like "web=3,worker=2" or
+// "all=2,web=3" into process counts. The "all" key sets the default count.
+func ParseFormation(formation string) (map[string]int, error) {
+	counts := make(map[string]int)
+
+	for _, part := range strings.Split(formation, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		name, value, found := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid formation entry %q (expected name=count)", part)
+		}
+
+		count, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || count < 1 || count > maxScale {
+			return nil, fmt.Errorf("invalid count in formation entry %q", part)
+		}
+
+		counts[name] = count
+	}
+
+	return counts, nil
+}
+
+// applyFormation resolves the instance count of each process type from the
+// per-process scale option and the formation string (which wins)
+func applyFormation(definitions []ProcessDefinition, formation string) (map[string]int, error) {
+	counts, err := ParseFormation(formation)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(definitions))
+	for _, def := range definitions {
+		known[def.Name] = true
+	}
+	for name := range counts {
+		if name != "all" && !known[name] {
+			return nil, fmt.Errorf("formation references unknown process %q", name)
+		}
+	}
+
+	result := make(map[string]int, len(definitions))
+	for _, def := range definitions {
+		count := 1
+		if def.Scale > 0 {
+			count = def.Scale
+		}
+		if all, ok := counts["all"]; ok {
+			count = all
+		}
+		if n, ok := counts[def.Name]; ok {
+			count = n
+		}
+		if count > maxScale {
+			return nil, fmt.Errorf("process %q: scale %d exceeds the maximum of %d", def.Name, count, maxScale)
+		}
+		result[def.Name] = count
+	}
+
+	return result, nil
+}
+
+// instanceNames returns the instance names of a process type: the plain name
+// when it runs once, "name.1", "name.2", ... when scaled
+func instanceNames(typeName string, count int) []string {
+	if count <= 1 {
+		return []string{typeName}
+	}
+
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("%s.%d", typeName, i+1)
+	}
+	return names
+}
+
+// instanceNumber returns the 1-based instance number encoded in an instance name
+func instanceNumber(name string, typeName string) int {
+	if n, err := strconv.Atoi(strings.TrimPrefix(name, typeName+".")); err == nil && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// instancesOf returns the current instance names of a process type. Must be
+// called with s.mu held.
+func (s *Supervisor) instancesOf(typeName string) []string {
+	return instanceNames(typeName, s.formation[typeName])
+}
+
+// resolveProcess maps a process type or instance name to its definition and
+// the instance names it covers. Must be called with s.mu held.
+func (s *Supervisor) resolveProcess(name string) (ProcessDefinition, []string, bool) {
+	if def, exists := s.processes[name]; exists {
+		return def, s.instancesOf(name), true
+	}
+
+	// Instance name like "web.2"
+	if i := strings.LastIndex(name, "."); i > 0 {
+		typeName := name[:i]
+		def, exists := s.processes[typeName]
+		if exists {
+			for _, instance := range s.instancesOf(typeName) {
+				if instance == name {
+					return def, []string{name}, true
+				}
+			}
+		}
+	}
+
+	return ProcessDefinition{}, nil, false
+}
+
+// instancePort returns the PORT of a process instance: the type's base port
+// (base_port + 100 per Procfile position, or its own port option) plus the
+// instance offset, like foreman does. Must be called with s.mu held.
+func (s *Supervisor) instancePort(def ProcessDefinition, instance int) int {
+	return s.ports[def.Name] + instance - 1
+}
+
+// processInfos returns the process list for the event sink, one entry per instance
+func (s *Supervisor) processInfos(definitions []ProcessDefinition) []ProcessInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]ProcessInfo, 0, len(definitions))
+	for _, def := range definitions {
+		if def.Disabled {
+			infos = append(infos, ProcessInfo{Name: def.Name, Type: def.Name, Disabled: true})
+			continue
+		}
+		for _, instance := range s.instancesOf(def.Name) {
+			infos = append(infos, ProcessInfo{Name: instance, Type: def.Name})
+		}
+	}
+	return infos
+}
+
+// Scale changes the number of instances of a process type. If the type
+// is running, removed instances are stopped and new ones started. Switching
+// between one and several instances renames them (web <-> web.1), which
+// restarts the running instance under its new name.
+func (s *Supervisor) Scale(name string, count int) error {
+	if count < 1 || count > maxScale {
+		return fmt.Errorf("scale must be between 1 and %d", maxScale)
+	}
+
+	s.mu.Lock()
+	def, exists := s.processes[name]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("unknown process %q", name)
+	}
+	if def.Disabled {
+		s.mu.Unlock()
+		return fmt.Errorf("process %q is disabled", name)
+	}
+
+	oldInstances := s.instancesOf(name)
+	s.formation[name] = count
+	newInstances := s.instancesOf(name)
+
+	wasRunning := false
+	for _, instance := range oldInstances {
+		if _, running := s.running[instance]; running {
+			wasRunning = true
+		}
+	}
+	s.mu.Unlock()
+
+	keep := make(map[string]bool, len(newInstances))
+	for _, instance := range newInstances {
+		keep[instance] = true
+	}
+
+	// Stop instances that no longer exist, highest first
+	for i := len(oldInstances) - 1; i >= 0; i-- {
+		if !keep[oldInstances[i]] {
+			s.stopProcess(oldInstances[i])
+		}
+	}
+
+	infos := make([]ProcessInfo, 0, len(newInstances))
+	for _, instance := range newInstances {
+		infos = append(infos, ProcessInfo{Name: instance, Type: name})
+	}
+	s.sink.OnScaled(ProcessScaled{
+		Type:      name,
+		Instances: infos,
+	})
+
+	if !wasRunning {
+		return nil
+	}
+
+	for _, instance := range newInstances {
+		s.resetRestarts(instance)
+		if err := s.spawnProcess(instance, def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
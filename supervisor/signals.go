@@ -0,0 +1,117 @@
+package supervisor
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"syscall"
+)
+
+// signalNames maps the signal names accepted in options and bindings
+var signalNames = map[string]syscall.Signal{
+	"TERM":  syscall.SIGTERM,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"HUP":   syscall.SIGHUP,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"KILL":  syscall.SIGKILL,
+	"WINCH": syscall.SIGWINCH,
+	"CONT":  syscall.SIGCONT,
+	"STOP":  syscall.SIGSTOP,
+	"TSTP":  syscall.SIGTSTP,
+	"ALRM":  syscall.SIGALRM,
+}
+
+// stopSignals are the signals allowed as stop_signal; others would suspend
+// or ignore the stop and leave it to SIGKILL
+var stopSignals = map[syscall.Signal]bool{
+	syscall.SIGTERM: true,
+	syscall.SIGINT:  true,
+	syscall.SIGQUIT: true,
+}
+
+// parseStopSignal accepts a stop signal name: TERM, INT or QUIT (with or
+// without the SIG prefix)
+func parseStopSignal(name string) (syscall.Signal, error) {
+	sig, err := parseSignal(name)
+	if err != nil {
+		return 0, err
+	}
+	if !stopSignals[sig] {
+		return 0, fmt.Errorf("%s can't be a stop signal (use TERM, INT or QUIT)", SignalName(sig))
+	}
+	return sig, nil
+}
+
+// parseSignal accepts a signal name like "TERM", "SIGTERM" or "sigterm"
+func parseSignal(name string) (syscall.Signal, error) {
+	key := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(name)), "SIG")
+	if sig, ok := signalNames[key]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", name)
+}
+
+// SignalName returns the conventional name of a signal, e.g. "SIGTERM"
+func SignalName(sig syscall.Signal) string {
+	for name, s := range signalNames {
+		if s == sig {
+			return "SIG" + name
+		}
+	}
+	return sig.String()
+}
+
+// Signal sends a signal to a running process: all instances of a
+// process type, or a single instance. The whole process group receives it
+// unless leaderOnly is set, in which case only the process started for the
+// Procfile command (usually the shell) gets it.
+func (s *Supervisor) Signal(name string, signal string, leaderOnly bool) error {
+	sig, err := parseSignal(signal)
+	if err != nil {
+		return err
+	}
+
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("signals are not supported on Windows")
+	}
+
+	s.mu.Lock()
+	_, instances, exists := s.resolveProcess(name)
+	if !exists {
+		instances = []string{name}
+	}
+	handles := make(map[string]*ProcessHandle)
+	for _, instance := range instances {
+		if handle, running := s.running[instance]; running {
+			handles[instance] = handle
+		}
+	}
+	s.mu.Unlock()
+
+	if len(handles) == 0 {
+		return fmt.Errorf("process %s is not running", name)
+	}
+
+	for _, instance := range instances {
+		handle, running := handles[instance]
+		if !running {
+			continue
+		}
+
+		// Negative PID targets the process group
+		target, targetDesc := -handle.pgid, fmt.Sprintf("process group %d", handle.pgid)
+		if leaderOnly || handle.pgid <= 0 {
+			target, targetDesc = handle.cmd.Process.Pid, fmt.Sprintf("pid %d", handle.cmd.Process.Pid)
+		}
+
+		if err := syscall.Kill(target, sig); err != nil {
+			return fmt.Errorf("failed to send %s to %s: %w", SignalName(sig), instance, err)
+		}
+
+		s.emitOutput(instance, fmt.Sprintf("Sent %s to %s", SignalName(sig), targetDesc), false)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package supervisor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ptyEOF is the terminal EOF character (Ctrl-D), only honored at the start of a line
+const ptyEOF = "\x04"
+
+// runningInstance returns the handle of a running process instance. A process
+// type name is accepted as long as it runs a single instance.
+func (s *Supervisor) runningInstance(name string) (*ProcessHandle, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, instances, ok := s.resolveProcess(name)
+	if !ok {
+		return nil, "", fmt.Errorf("unknown process %q", name)
+	}
+	if len(instances) != 1 {
+		return nil, "", fmt.Errorf("process %q runs %d instances, pick one (e.g. %s)", name, len(instances), instances[0])
+	}
+
+	handle, exists := s.running[instances[0]]
+	if !exists {
+		return nil, "", fmt.Errorf("process %q is not running", instances[0])
+	}
+	return handle, instances[0], nil
+}
+
+// WriteStdin sends data to the stdin of a running process and echoes it
+// into the log. The data is written as-is, so include a trailing newline to
+// submit a line.
+func (s *Supervisor) WriteStdin(name string, data string) error {
+	handle, instance, err := s.runningInstance(name)
+	if err != nil {
+		return err
+	}
+
+	handle.stdinMu.Lock()
+	defer handle.stdinMu.Unlock()
+
+	if handle.stdin == nil || handle.stdinClosed {
+		return fmt.Errorf("stdin of process %q is closed", instance)
+	}
+	if _, err := handle.stdin.Write([]byte(data)); err != nil {
+		return fmt.Errorf("write to stdin of process %q: %w", instance, err)
+	}
+
+	// Terminals echo input themselves
+	if handle.pty == nil {
+		for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+			s.emitOutput(instance, "> "+line, false)
+		}
+	}
+
+	return nil
+}
+
+// CloseStdin sends EOF to a running process. For pipes this closes stdin;
+// in PTY mode it sends Ctrl-D, which the terminal treats as EOF at the start
+// of a line and which can be sent more than once.
+func (s *Supervisor) CloseStdin(name string) error {
+	handle, instance, err := s.runningInstance(name)
+	if err != nil {
+		return err
+	}
+
+	handle.stdinMu.Lock()
+	defer handle.stdinMu.Unlock()
+
+	if handle.stdin == nil || handle.stdinClosed {
+		return fmt.Errorf("stdin of process %q is closed", instance)
+	}
+
+	if handle.pty != nil {
+		if _, err := handle.stdin.Write([]byte(ptyEOF)); err != nil {
+			return fmt.Errorf("send EOF to process %q: %w", instance, err)
+		}
+	} else {
+		handle.stdinClosed = true
+		if err := handle.stdin.Close(); err != nil {
+			return fmt.Errorf("close stdin of process %q: %w", instance, err)
+		}
+	}
+
+	s.emitOutput(instance, "> ^D (EOF)", false)
+	return nil
+}
@@ -0,0 +1,509 @@
+package supervisor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Supervisor runs the processes of a Procfile and reports what happens to
+// them through an EventSink
+type Supervisor struct {
+	sink              EventSink
+	processes         map[string]ProcessDefinition
+	order             []string       // process names in start order (dependencies first)
+	formation         map[string]int // instance count per process type
+	ports             map[string]int // base PORT per process type
+	running           map[string]*ProcessHandle
+	restarts          map[string]*restartState // auto-restart bookkeeping per process
+	logs              *logBuffer               // recent output of every process
+	logFiles          *logFileSet              // optional log files under the project
+	procfilePath      string
+	globalAutoRestart bool
+	termCols          int // window size for processes in PTY mode
+	termRows          int
+	sessionID         string                   // unique ID for this session to track orphaned processes (empty: don't tag)
+	envVars           map[string]string        // environment variables of the env files, merged
+	envLayers         []envLayer               // the env files in precedence order, for provenance
+	processEnv        map[string][]envLayer    // per-process env files and variables over envLayers
+	envPaths          []string                 // env files to watch, existing or not
+	cleanEnv          bool                     // start processes without the system environment but envAllowlist
+	envAllowlist      []string                 // system variables kept by cleanEnv beyond the defaults
+	redactor          atomic.Pointer[redactor] // masks secrets in output, nil when off
+
+	reload   reloadState // auto-reload of the Procfile on file changes
+	reloadMu sync.Mutex
+
+	statuses map[string]ProcessStatus // latest status per instance
+	statusMu sync.Mutex
+
+	live     atomic.Int64  // processes running or waiting to be restarted
+	killNow  chan struct{} // closed to skip the graceful stop timeout
+	killOnce sync.Once
+
+	mu sync.Mutex
+}
+
+// New creates a supervisor that reports to sink
+func New(sink EventSink) *Supervisor {
+	return &Supervisor{
+		sink:              sink,
+		processes:         make(map[string]ProcessDefinition),
+		formation:         make(map[string]int),
+		ports:             make(map[string]int),
+		running:           make(map[string]*ProcessHandle),
+		restarts:          make(map[string]*restartState),
+		logs:              newLogBuffer(logBufferCapacity),
+		logFiles:          &logFileSet{files: make(map[string]*rotatingLog)},
+		globalAutoRestart: true,
+		termCols:          120,
+		termRows:          40,
+		sessionID:         fmt.Sprintf("%d", time.Now().UnixNano()),
+		envVars:           make(map[string]string),
+		processEnv:        make(map[string][]envLayer),
+		statuses:          make(map[string]ProcessStatus),
+		killNow:           make(chan struct{}),
+	}
+}
+
+// SetSessionID sets the ID our processes are tagged with for orphan cleanup.
+// An empty ID leaves them untagged, so no session treats them as orphans.
+func (s *Supervisor) SetSessionID(id string) {
+	s.mu.Lock()
+	s.sessionID = id
+	s.mu.Unlock()
+}
+
+// SessionID returns the ID our processes are tagged with
+func (s *Supervisor) SessionID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionID
+}
+
+// sessionLookup resolves the variables the supervisor sets for every process
+// it starts, then the system environment
+func (s *Supervisor) sessionLookup(name string) (string, bool) {
+	if name == ProcessRunnerEnvKey {
+		if id := s.SessionID(); id != "" {
+			return id, true
+		}
+	}
+	return os.LookupEnv(name)
+}
+
+// ProcfilePath returns the path of the loaded Procfile
+func (s *Supervisor) ProcfilePath() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.procfilePath
+}
+
+// Definitions returns the loaded process definitions in start order
+func (s *Supervisor) Definitions() []ProcessDefinition {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	definitions := make([]ProcessDefinition, 0, len(s.order))
+	for _, name := range s.order {
+		definitions = append(definitions, s.processes[name])
+	}
+	return definitions
+}
+
+// Instances returns the instance names of a process type
+func (s *Supervisor) Instances(typeName string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.instancesOf(typeName)
+}
+
+// report records the latest status of an instance and passes it to the sink
+func (s *Supervisor) report(status ProcessStatus) {
+	s.statusMu.Lock()
+	// Readiness changes don't repeat the pid of the running process
+	if previous, ok := s.statuses[status.Name]; ok && status.PID == 0 && (status.Status == "ready" || status.Status == "unhealthy") {
+		status.PID = previous.PID
+	}
+	s.statuses[status.Name] = status
+	s.statusMu.Unlock()
+
+	s.sink.OnStatus(status)
+}
+
+// Status returns the latest status of every process instance in start
+// order. Instances that never ran are "stopped", commented-out processes
+// "disabled".
+func (s *Supervisor) Status() []ProcessStatus {
+	infos := s.processInfos(s.Definitions())
+
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+
+	statuses := make([]ProcessStatus, 0, len(infos))
+	for _, info := range infos {
+		status, ok := s.statuses[info.Name]
+		switch {
+		case info.Disabled:
+			status = ProcessStatus{Name: info.Name, Status: "disabled"}
+		case !ok:
+			status = ProcessStatus{Name: info.Name, Status: "stopped"}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Idle reports whether no process is running or waiting to be restarted
+func (s *Supervisor) Idle() bool {
+	return s.live.Load() == 0
+}
+
+// Close stops watching the Procfile, stops all processes and closes the log
+// files
+func (s *Supervisor) Close() {
+	s.SetAutoReload(false, false)
+	s.StopAll()
+	s.logFiles.close()
+}
+
+// Load loads and parses a Procfile with its side config and env files. A
+// directory loads the Procfile in it.
+func (s *Supervisor) Load(path string) error {
+	loaded, err := s.load(path)
+	if err != nil {
+		return err
+	}
+
+	s.activate()
+	s.sink.OnProcfileLoaded(loaded)
+	return nil
+}
+
+// activate finishes loading a Procfile, from Load or an auto-reload: pending
+// auto-restarts belong to the previous definitions and are dropped, and the
+// files of the new one (its env files may have changed) are followed when
+// auto-reload is on. It returns the processes whose restart was dropped.
+func (s *Supervisor) activate() []string {
+	dropped := s.resetAllRestarts()
+	s.watchProcfile()
+	return dropped
+}
+
+// load parses a Procfile with its side config and env files and makes it
+// the current one, without reporting it. Running processes keep running.
+func (s *Supervisor) load(path string) (ProcfileLoaded, error) {
+	path, err := ResolveProcfile(path)
+	if err != nil {
+		return ProcfileLoaded{}, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ProcfileLoaded{}, err
+	}
+
+	definitions := ParseProcfile(string(content))
+
+	// Apply per-process options from the side config if present
+	opts := &ProcfileOptions{}
+	optionsPath := FindOptionsFile(path)
+	if optionsPath != "" {
+		parsed, err := ParseOptionsFile(optionsPath)
+		if err != nil {
+			return ProcfileLoaded{}, err
+		}
+		if err := ApplyOptions(definitions, parsed); err != nil {
+			return ProcfileLoaded{}, err
+		}
+		opts = parsed
+	}
+
+	formation, err := applyFormation(definitions, opts.Formation)
+	if err != nil {
+		return ProcfileLoaded{}, err
+	}
+
+	// Resolve start order up front so cycles are reported at load time
+	ordered, err := SortByDependencies(definitions)
+	if err != nil {
+		return ProcfileLoaded{}, err
+	}
+
+	// Load the env files that exist, later ones overriding earlier ones
+	envNames := opts.EnvFiles
+	if len(envNames) == 0 {
+		envNames = defaultEnvFiles
+	}
+	envLayers, err := loadEnvLayers(FindEnvFiles(path, envNames), nil, s.sessionLookup)
+	if err != nil {
+		return ProcfileLoaded{}, err
+	}
+	envVars := mergeEnvLayers(envLayers)
+
+	// Per-process env files and variables go on top
+	envPaths := envFilePaths(path, envNames)
+	processEnv := make(map[string][]envLayer)
+	for _, def := range definitions {
+		layers, err := processEnvLayers(path, def, envLayers, s.sessionLookup)
+		if err != nil {
+			return ProcfileLoaded{}, err
+		}
+		if len(layers) > 0 {
+			processEnv[def.Name] = layers
+		}
+		envPaths = append(envPaths, envFilePaths(path, def.EnvFiles)...)
+	}
+
+	// Mask the secrets of every environment a process may get
+	redactLayers := append([]envLayer{systemEnvLayer(false, nil)}, envLayers...)
+	for _, layers := range processEnv {
+		redactLayers = append(redactLayers, layers...)
+	}
+	redactor, err := newRedactor(opts.Redact, redactLayers)
+	if err != nil {
+		return ProcfileLoaded{}, err
+	}
+
+	// Hand out ports like foreman: base port + 100 per Procfile position
+	basePort := opts.BasePort
+	if basePort == 0 {
+		if envPort, err := strconv.Atoi(envVars["PORT"]); err == nil && envPort > 0 {
+			basePort = envPort
+		} else {
+			basePort = defaultBasePort
+		}
+	}
+	ports := make(map[string]int, len(definitions))
+	for i, def := range definitions {
+		ports[def.Name] = basePort + i*100
+		if def.Port > 0 {
+			ports[def.Name] = def.Port
+		}
+	}
+
+	s.mu.Lock()
+	// Log history is kept across reloads of the same Procfile
+	if s.procfilePath != path {
+		s.logs.clear("")
+	}
+	s.procfilePath = path
+	s.envVars = envVars
+	s.envLayers = envLayers
+	s.processEnv = processEnv
+	s.envPaths = envPaths
+	s.cleanEnv = opts.CleanEnv
+	s.envAllowlist = opts.EnvAllowlist
+	s.redactor.Store(redactor)
+	s.formation = formation
+	s.ports = ports
+	s.processes = make(map[string]ProcessDefinition)
+	for _, def := range definitions {
+		s.processes[def.Name] = def
+	}
+	s.order = make([]string, 0, len(ordered))
+	for _, def := range ordered {
+		s.order = append(s.order, def.Name)
+	}
+	s.mu.Unlock()
+
+	s.logFiles.configure(path, opts.Logs)
+
+	// Process info for the event (one entry per instance) with env info
+	return ProcfileLoaded{
+		Path:      path,
+		Processes: s.processInfos(definitions),
+		EnvLoaded: len(envVars) > 0,
+		EnvCount:  len(envVars),
+		EnvFiles:  loadedEnvFiles(envLayers),
+	}, nil
+}
+
+// Start starts a process by type (all its instances) or instance name
+func (s *Supervisor) Start(name string) error {
+	s.mu.Lock()
+	def, instances, exists := s.resolveProcess(name)
+	s.mu.Unlock()
+
+	if !exists {
+		return nil // Process not found, not an error
+	}
+
+	for _, instance := range instances {
+		// A manual start clears the crash-loop history
+		s.resetRestarts(instance)
+
+		if err := s.spawnProcess(instance, def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stop stops a process by type (all its instances) or instance name
+func (s *Supervisor) Stop(name string) error {
+	s.mu.Lock()
+	_, instances, exists := s.resolveProcess(name)
+	s.mu.Unlock()
+
+	if !exists {
+		// Not in the current Procfile, but may still be running
+		return s.stopProcess(name)
+	}
+
+	for _, instance := range instances {
+		s.stopProcess(instance)
+	}
+
+	return nil
+}
+
+// Restart restarts a process by type (all its instances) or instance name
+func (s *Supervisor) Restart(name string) error {
+	s.mu.Lock()
+	def, instances, exists := s.resolveProcess(name)
+	s.mu.Unlock()
+
+	if !exists {
+		return nil
+	}
+
+	for _, instance := range instances {
+		// Stop if running (also clears the crash-loop history)
+		s.stopProcess(instance)
+
+		// Start again
+		if err := s.spawnProcess(instance, def); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StartAll starts all enabled processes defined in the Procfile in
+// dependency order, waiting for each dependency to become ready first
+func (s *Supervisor) StartAll() error {
+	return s.startProcesses(nil)
+}
+
+// StartTypes starts the given process types in dependency order, waiting for
+// each dependency to become ready first. Dependencies are not added; see
+// WithDependencies.
+func (s *Supervisor) StartTypes(names []string) error {
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+	return s.startProcesses(selected)
+}
+
+// startProcesses starts the selected process types (all enabled ones when
+// nil) in dependency order, waiting for each dependency to become ready first
+func (s *Supervisor) startProcesses(selected map[string]bool) error {
+	s.mu.Lock()
+	definitions := make([]ProcessDefinition, 0, len(s.order))
+	for _, name := range s.order {
+		def := s.processes[name]
+		if selected == nil && !def.Disabled || selected[name] {
+			definitions = append(definitions, def)
+		}
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, def := range definitions {
+		// Skip instances that are already running
+		s.mu.Lock()
+		var stopped []string
+		for _, instance := range s.instancesOf(def.Name) {
+			if _, running := s.running[instance]; !running {
+				stopped = append(stopped, instance)
+			}
+		}
+		s.mu.Unlock()
+
+		if len(stopped) == 0 {
+			continue
+		}
+
+		// Wait for dependencies; a failed dependency skips its dependents
+		if err := s.waitForDependencies(def); err != nil {
+			for _, instance := range stopped {
+				s.emitOutput(instance, fmt.Sprintf("Not starting: %v", err), true)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", def.Name, err))
+			continue
+		}
+
+		for _, instance := range stopped {
+			s.resetRestarts(instance)
+			if err := s.spawnProcess(instance, def); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", instance, err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// StopAll stops all running processes in parallel, dependents before their
+// dependencies
+func (s *Supervisor) StopAll() error {
+	// Cancel pending auto-restarts first so nothing comes back up
+	for _, name := range s.resetAllRestarts() {
+		s.report(ProcessStatus{
+			Name:     name,
+			Status:   "stopped",
+			ExitCode: nil,
+		})
+	}
+
+	s.mu.Lock()
+	waves := s.stopWaves()
+	s.mu.Unlock()
+
+	for _, names := range waves {
+		var wg sync.WaitGroup
+		for _, name := range names {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				s.stopProcess(name)
+			}(name)
+		}
+		wg.Wait()
+	}
+
+	return nil
+}
+
+// SetAutoRestart sets the restart policy of processes without their own
+// restart option: on-failure when enabled, never otherwise
+func (s *Supervisor) SetAutoRestart(enabled bool) {
+	s.mu.Lock()
+	s.globalAutoRestart = enabled
+	s.mu.Unlock()
+}
+
+// SetTerminalSize sets the window size for processes in PTY mode and
+// propagates it to the running ones
+func (s *Supervisor) SetTerminalSize(cols int, rows int) {
+	if cols <= 0 || rows <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	s.termCols = cols
+	s.termRows = rows
+	for _, handle := range s.running {
+		if handle.pty != nil {
+			setPTYSize(handle.pty, cols, rows)
+		}
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,104 @@
+package supervisor
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// watchSettle is how long files must stay unchanged before a reload; editors
+// and git write files in several steps
+const watchSettle = 200 * time.Millisecond
+
+// fileStamp identifies a version of a file
+type fileStamp struct {
+	exists  bool
+	size    int64
+	modTime int64
+}
+
+// stampOf returns the current version of a file
+func stampOf(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{exists: true, size: info.Size(), modTime: info.ModTime().UnixNano()}
+}
+
+// fileWatcher calls changed when one of its files is created, written or
+// removed. The platform part (inotify on Linux, polling elsewhere) pokes
+// it; it then waits for the files to settle and compares their stamps, so
+// touching unrelated files in the same directory doesn't reload.
+type fileWatcher struct {
+	paths   []string
+	stamps  map[string]fileStamp
+	changed func()
+	timer   *time.Timer
+	stop    func() // stops the platform part
+	closed  bool
+	mu      sync.Mutex
+}
+
+// watchFiles starts watching paths, which need not exist yet
+func watchFiles(paths []string, changed func()) (*fileWatcher, error) {
+	w := &fileWatcher{
+		paths:   paths,
+		stamps:  make(map[string]fileStamp, len(paths)),
+		changed: changed,
+	}
+	for _, path := range paths {
+		w.stamps[path] = stampOf(path)
+	}
+
+	stop, err := w.start()
+	if err != nil {
+		return nil, err
+	}
+	w.stop = stop
+	return w, nil
+}
+
+// poke schedules a check once the files have settled
+func (w *fileWatcher) poke() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.timer = time.AfterFunc(watchSettle, w.check)
+}
+
+// check calls changed when a file differs from its last known version
+func (w *fileWatcher) check() {
+	w.mu.Lock()
+	changed := false
+	for _, path := range w.paths {
+		if stamp := stampOf(path); stamp != w.stamps[path] {
+			w.stamps[path] = stamp
+			changed = true
+		}
+	}
+	closed := w.closed
+	w.mu.Unlock()
+
+	if changed && !closed {
+		w.changed()
+	}
+}
+
+// close stops watching; a pending check is dropped
+func (w *fileWatcher) close() {
+	w.mu.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mu.Unlock()
+
+	w.stop()
+}
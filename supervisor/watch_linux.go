@@ -0,0 +1,99 @@
+package supervisor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchEvents are the inotify events that may change a watched file
+const watchEvents = syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY | syscall.IN_ATTRIB |
+	syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// start watches the directories of the files with inotify, so files that
+// are replaced by a rename (as most editors save) or created later are seen.
+// A directory that doesn't exist yet is watched through its nearest existing
+// parent until it is created.
+func (w *fileWatcher) start() (func(), error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	names := make(map[string]bool, len(w.paths))
+	dirs := make(map[string]bool)
+	for _, path := range w.paths {
+		names[filepath.Base(path)] = true
+		dirs[filepath.Dir(path)] = true
+	}
+
+	// addWatches watches every directory that exists and the nearest
+	// existing parent of the others, listening for the missing entry
+	watched := make(map[string]bool)
+	addWatches := func() error {
+		for dir := range dirs {
+			for d := dir; !watched[d]; {
+				_, err := syscall.InotifyAddWatch(fd, d, watchEvents)
+				if err == nil {
+					watched[d] = true
+					break
+				}
+				parent := filepath.Dir(d)
+				if (err != syscall.ENOENT && err != syscall.ENOTDIR) || parent == d {
+					return os.NewSyscallError("inotify_add_watch", err)
+				}
+				names[filepath.Base(d)] = true
+				d = parent
+			}
+		}
+		return nil
+	}
+	if err := addWatches(); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+
+	// A non-blocking fd goes through the runtime poller, so closing the file
+	// ends the pending Read
+	file := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, err := file.Read(buf)
+			if err != nil {
+				return
+			}
+			if watchedEvent(buf[:n], names) {
+				// A missing directory may have been created meanwhile
+				addWatches()
+				w.poke()
+			}
+		}
+	}()
+
+	return func() { file.Close() }, nil
+}
+
+// watchedEvent reports whether a batch of inotify events names one of the
+// watched files (or overflowed, so anything may have changed)
+func watchedEvent(buf []byte, names map[string]bool) bool {
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		start := offset + syscall.SizeofInotifyEvent
+		end := start + int(event.Len)
+		if end > len(buf) {
+			return true
+		}
+		if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			return true
+		}
+		name := string(bytes.TrimRight(buf[start:end], "\x00"))
+		if names[name] {
+			return true
+		}
+		offset = end
+	}
+	return false
+}
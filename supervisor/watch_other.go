@@ -0,0 +1,27 @@
+//go:build !linux
+
+package supervisor
+
+import "time"
+
+// watchPollInterval is how often the files are checked without inotify
+const watchPollInterval = time.Second
+
+// start polls the files, as there's no inotify here
+func (w *fileWatcher) start() (func(), error) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(watchPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.check()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }, nil
+}
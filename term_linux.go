@@ -0,0 +1,9 @@
+package main
+
+import "syscall"
+
+// ioctl requests for the terminal attributes
+const (
+	ioctlReadTermios  = syscall.TCGETS
+	ioctlWriteTermios = syscall.TCSETS
+)
@@ -0,0 +1,21 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is only implemented on Linux and macOS
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("the terminal UI is not supported on this platform")
+}
+
+// terminalSize is only implemented on Linux and macOS
+func terminalSize(f *os.File) (int, int, error) {
+	return 0, 0, errors.New("the terminal UI is not supported on this platform")
+}
+
+// notifyResize is only implemented on Linux and macOS
+func notifyResize(ch chan<- os.Signal) {}
@@ -0,0 +1,59 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal into raw mode (no echo, no line buffering, no
+// signals from Ctrl-C) and returns a function that restores the old mode
+func makeRaw(f *os.File) (func(), error) {
+	var old syscall.Termios
+	if err := termios(f, ioctlReadTermios, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termios(f, ioctlWriteTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() { termios(f, ioctlWriteTermios, &old) }, nil
+}
+
+// termios reads or writes the terminal attributes of f
+func termios(f *os.File, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// terminalSize returns the size of the terminal f in columns and rows
+func terminalSize(f *os.File) (int, int, error) {
+	var ws struct {
+		Row, Col, X, Y uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, 0, errno
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// notifyResize sends a value on ch whenever the terminal is resized
+func notifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
@@ -0,0 +1,658 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"procfile-runner/supervisor"
+)
+
+// tuiMaxLines is how many output lines the terminal UI keeps
+const tuiMaxLines = 10000
+
+// tuiFrameInterval limits redraws while output streams in
+const tuiFrameInterval = 50 * time.Millisecond
+
+// tuiStatusColors are the ANSI colors of process states in the sidebar
+var tuiStatusColors = map[string]string{
+	"running":    "32",
+	"ready":      "32",
+	"starting":   "33",
+	"unhealthy":  "33",
+	"restarting": "33",
+	"crashed":    "31",
+	"stopped":    "90",
+	"disabled":   "2",
+}
+
+const tuiHelp = "↑↓ select  s start  x stop  r restart  A/S/R all  / search  ^L clear  PgUp/PgDn scroll  p ports  q quit"
+
+const tuiPortsHelp = "↑↓ select  x kill  p/Esc back to logs  q quit"
+
+// tui is the interactive terminal UI: a process sidebar, a log pane with
+// filtering and search, and a ports panel. It is the EventSink of the
+// supervisor it drives.
+type tui struct {
+	sup       *supervisor.Supervisor
+	path      string
+	lines     []supervisor.ProcessOutput
+	colors    map[string]string // per instance, like the headless output
+	selected  int               // sidebar row: 0 is "All", then the instances
+	filter    string            // instance shown in the log pane, "" for all
+	scroll    int               // lines scrolled up from the bottom of the log pane
+	search    string
+	searching bool // typing into the search field
+	showPorts bool
+	ports     []PortInfo
+	portSel   int
+	message   string // result of the last action, shown in the footer
+	width     int
+	height    int
+	quitting  bool
+	dirty     chan struct{} // signals that a redraw is due
+	mu        sync.Mutex
+}
+
+// newTUI creates a terminal UI; set sup before it handles keys
+func newTUI() *tui {
+	return &tui{
+		colors: make(map[string]string),
+		width:  80,
+		height: 24,
+		dirty:  make(chan struct{}, 1),
+	}
+}
+
+// runTUI runs the terminal UI until the user quits and all processes stopped
+func runTUI(args []string) int {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	procfile := flags.String("f", "Procfile", "path to the Procfile")
+	watch := flags.Bool("watch", false, "reload the Procfile and .env on change, restarting changed processes")
+	if err := flags.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "tui: %v\n\n%s", err, cliUsage)
+		return exitUsage
+	}
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintln(os.Stderr, "tui: needs a terminal, use `start` for plain output")
+		return exitUsage
+	}
+
+	path, err := filepath.Abs(*procfile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+
+	t := newTUI()
+	control := newControlServer()
+	t.sup = supervisor.New(supervisor.MultiSink{t, control})
+	t.sup.SetSessionID("")
+	t.path = path
+	if err := t.sup.Load(path); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", *procfile, err)
+		return exitUsage
+	}
+	if *watch {
+		t.sup.SetAutoReload(true, true)
+	}
+	if socketPath, err := getControlSocketPath(); err == nil {
+		if err := control.listen(socketPath, t.sup); err != nil {
+			t.message = fmt.Sprintf("Control socket disabled: %v", err)
+		}
+		defer control.close()
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "tui: %v\n", err)
+		return exitFailed
+	}
+	// Alternate screen, hidden cursor; undone in reverse on exit
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	t.resize()
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+
+	keys := make(chan string, 64)
+	go readKeys(os.Stdin, keys)
+
+	stopped := make(chan struct{})
+	ticker := time.NewTicker(tuiFrameInterval)
+	defer ticker.Stop()
+	t.redraw()
+
+	pending := false
+	for {
+		select {
+		case key := <-keys:
+			if t.handleKey(key) {
+				go func() {
+					t.sup.StopAll()
+					close(stopped)
+				}()
+			}
+			pending = true
+		case <-resized:
+			t.resize()
+			pending = true
+		case <-t.dirty:
+			pending = true
+		case <-ticker.C:
+			if pending {
+				t.redraw()
+				pending = false
+			}
+		case <-stopped:
+			return exitOK
+		}
+	}
+}
+
+// resize reads the terminal size
+func (t *tui) resize() {
+	cols, rows, err := terminalSize(os.Stdout)
+	if err != nil || cols < 40 || rows < 5 {
+		return
+	}
+	t.mu.Lock()
+	t.width, t.height = cols, rows
+	t.mu.Unlock()
+}
+
+// redraw writes a full frame to the terminal
+func (t *tui) redraw() {
+	os.Stdout.WriteString(t.frame())
+}
+
+// markDirty schedules a redraw
+func (t *tui) markDirty() {
+	select {
+	case t.dirty <- struct{}{}:
+	default:
+	}
+}
+
+// OnOutput keeps a line of output for the log pane
+func (t *tui) OnOutput(output supervisor.ProcessOutput) {
+	t.mu.Lock()
+	t.lines = append(t.lines, output)
+	if len(t.lines) > tuiMaxLines {
+		// Drop in chunks so appending stays cheap
+		t.lines = append([]supervisor.ProcessOutput(nil), t.lines[len(t.lines)-tuiMaxLines*9/10:]...)
+	}
+	// Keep a scrolled-up view where it is
+	if t.scroll > 0 && t.matches(output) {
+		t.scroll++
+	}
+	t.mu.Unlock()
+	t.markDirty()
+}
+
+// OnStatus redraws the sidebar
+func (t *tui) OnStatus(status supervisor.ProcessStatus) {
+	t.markDirty()
+}
+
+// OnProcfileLoaded redraws the sidebar
+func (t *tui) OnProcfileLoaded(loaded supervisor.ProcfileLoaded) {
+	t.mu.Lock()
+	t.path = loaded.Path
+	t.mu.Unlock()
+	t.markDirty()
+}
+
+// OnScaled redraws the sidebar
+func (t *tui) OnScaled(scaled supervisor.ProcessScaled) {
+	t.markDirty()
+}
+
+// OnProcfileReloaded redraws the sidebar and tells what changed
+func (t *tui) OnProcfileReloaded(reloaded supervisor.ProcfileReloaded) {
+	t.mu.Lock()
+	t.message = reloaded.Summary()
+	t.mu.Unlock()
+	t.markDirty()
+}
+
+// statuses returns the process instances for the sidebar, assigns colors to
+// new ones and updates the filter. Must be called with t.mu held.
+func (t *tui) statuses() []supervisor.ProcessStatus {
+	statuses := t.sup.Status()
+
+	var names []string
+	for _, status := range statuses {
+		names = append(names, status.Name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		t.colors[name] = cliColors[i%len(cliColors)]
+	}
+
+	t.selected = min(t.selected, len(statuses))
+	t.filter = ""
+	if t.selected > 0 {
+		t.filter = statuses[t.selected-1].Name
+	}
+	return statuses
+}
+
+// matches reports whether a line passes the process filter and the search.
+// Must be called with t.mu held.
+func (t *tui) matches(output supervisor.ProcessOutput) bool {
+	if t.filter != "" && output.Name != t.filter {
+		return false
+	}
+	if t.search != "" && !strings.Contains(strings.ToLower(supervisor.StripANSI(output.Line)), strings.ToLower(t.search)) {
+		return false
+	}
+	return true
+}
+
+// handleKey applies a key press and reports whether the UI should start
+// shutting down
+func (t *tui) handleKey(key string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if key == "ctrl-c" || (key == "q" && !t.searching) {
+		if t.quitting {
+			t.message = "Killing all processes..."
+			t.sup.ForceStop()
+			return false
+		}
+		t.quitting = true
+		t.message = "Stopping all processes (press q again to kill)..."
+		return true
+	}
+
+	if t.searching {
+		switch key {
+		case "enter":
+			t.searching = false
+		case "esc":
+			t.searching = false
+			t.search = ""
+		case "backspace":
+			if t.search != "" {
+				_, size := utf8.DecodeLastRuneInString(t.search)
+				t.search = t.search[:len(t.search)-size]
+			}
+		default:
+			if utf8.RuneCountInString(key) == 1 {
+				t.search += key
+			}
+		}
+		t.scroll = 0
+		return false
+	}
+
+	if t.showPorts {
+		switch key {
+		case "up", "k":
+			if t.portSel > 0 {
+				t.portSel--
+			}
+		case "x":
+			if len(t.ports) > 0 {
+				port := t.ports[t.portSel]
+				t.run(fmt.Sprintf("Killing port %d...", port.Port), fmt.Sprintf("Killed process on port %d", port.Port), func() error {
+					defer t.refreshPorts()
+					return killPort(port.Port)
+				})
+			}
+		case "down", "j":
+			if t.portSel < len(t.ports)-1 {
+				t.portSel++
+			}
+		case "p", "esc":
+			t.showPorts = false
+		}
+		return false
+	}
+
+	count := len(t.statuses())
+	name := t.filter
+	switch key {
+	case "up", "k":
+		if t.selected > 0 {
+			t.selected--
+			t.scroll = 0
+			t.statuses()
+		}
+	case "down", "j":
+		if t.selected < count {
+			t.selected++
+			t.scroll = 0
+			t.statuses()
+		}
+	case "pgup":
+		t.scroll += t.height / 2
+	case "pgdn":
+		t.scroll -= t.height / 2
+		if t.scroll < 0 {
+			t.scroll = 0
+		}
+	case "home":
+		t.scroll = len(t.lines)
+	case "end":
+		t.scroll = 0
+	case "s", "x", "r":
+		if name == "" {
+			t.message = "Select a process first (A/S/R act on all)"
+			break
+		}
+		switch key {
+		case "s":
+			t.run("Starting "+name+"...", "", func() error { return t.sup.Start(name) })
+		case "x":
+			t.run("Stopping "+name+"...", "", func() error { return t.sup.Stop(name) })
+		case "r":
+			t.run("Restarting "+name+"...", "", func() error { return t.sup.Restart(name) })
+		}
+	case "A":
+		t.run("Starting all processes...", "", t.sup.StartAll)
+	case "S":
+		t.run("Stopping all processes...", "", t.sup.StopAll)
+	case "R":
+		t.run("Restarting all processes...", "", func() error {
+			if err := t.sup.StopAll(); err != nil {
+				return err
+			}
+			return t.sup.StartAll()
+		})
+	case "/", "ctrl-f":
+		t.searching = true
+	case "esc":
+		t.search = ""
+		t.scroll = 0
+	case "ctrl-l":
+		t.lines = nil
+		t.scroll = 0
+		t.sup.ClearLogs("")
+	case "p":
+		t.showPorts = true
+		t.portSel = 0
+		t.message = "Scanning ports..."
+		go t.refreshPorts()
+	}
+	return false
+}
+
+// run starts a slow action in the background, showing pending in the footer
+// until it finishes and then done or the error. Must be called with t.mu held.
+func (t *tui) run(pending string, done string, action func() error) {
+	t.message = pending
+	go func() {
+		err := action()
+		t.mu.Lock()
+		if err != nil {
+			t.message = "Error: " + err.Error()
+		} else if t.message == pending {
+			t.message = done
+		}
+		t.mu.Unlock()
+		t.markDirty()
+	}()
+}
+
+// refreshPorts rescans the listening ports for the ports panel
+func (t *tui) refreshPorts() {
+	ports := activePorts()
+	t.mu.Lock()
+	t.ports = ports
+	if t.portSel >= len(ports) {
+		t.portSel = max(len(ports)-1, 0)
+	}
+	if t.message == "Scanning ports..." {
+		t.message = ""
+	}
+	t.mu.Unlock()
+	t.markDirty()
+}
+
+// frame renders the whole screen
+func (t *tui) frame() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	width, height := t.width, t.height
+	statuses := t.statuses()
+	bodyHeight := height - 2
+
+	// Sidebar fits the longest name plus its status
+	sidebarWidth := len("All")
+	for _, status := range statuses {
+		sidebarWidth = max(sidebarWidth, len(status.Name))
+	}
+	sidebarWidth = min(max(sidebarWidth+16, 24), width/3)
+	paneWidth := width - sidebarWidth - 1
+
+	running := 0
+	for _, status := range statuses {
+		if status.Status == "running" || status.Status == "ready" || status.Status == "starting" || status.Status == "unhealthy" {
+			running++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	header := fmt.Sprintf(" Procfile Runner  %s", t.path)
+	counts := fmt.Sprintf("%d/%d running ", running, len(statuses))
+	sb.WriteString("\x1b[7m" + fitWidth(header, width-len(counts)) + counts + "\x1b[0m")
+
+	sidebar := t.sidebarRows(statuses, sidebarWidth, bodyHeight)
+	var pane []string
+	if t.showPorts {
+		pane = t.portRows(paneWidth, bodyHeight)
+	} else {
+		pane = t.logRows(paneWidth, bodyHeight)
+	}
+	for row := 0; row < bodyHeight; row++ {
+		fmt.Fprintf(&sb, "\x1b[%d;1H%s\x1b[90m│\x1b[0m%s", row+2, sidebar[row], pane[row])
+	}
+
+	footer := t.message
+	switch {
+	case t.searching:
+		footer = "Search: " + t.search + "█"
+	case footer == "" && t.showPorts:
+		footer = tuiPortsHelp
+	case footer == "":
+		footer = tuiHelp
+	}
+	if t.search != "" && !t.searching {
+		footer = fmt.Sprintf("[/%s] %s", t.search, footer)
+	}
+	fmt.Fprintf(&sb, "\x1b[%d;1H\x1b[7m%s\x1b[0m", height, fitWidth(" "+footer, width))
+	return sb.String()
+}
+
+// sidebarRows renders the process list. Must be called with t.mu held.
+func (t *tui) sidebarRows(statuses []supervisor.ProcessStatus, width int, height int) []string {
+	rows := make([]string, height)
+	entries := []string{fitWidth(" All", width)}
+	for _, status := range statuses {
+		color := tuiStatusColors[status.Status]
+		if status.Status == "stopped" && status.ExitCode != nil && *status.ExitCode != 0 {
+			color = "31"
+		}
+		name := fitWidth(" "+status.Name, width-12)
+		entries = append(entries, fmt.Sprintf("\x1b[%sm●\x1b[0m%s\x1b[%sm%s\x1b[0m", color, name, color, fitWidth(" "+status.Status, 11)))
+	}
+
+	// Keep the selection visible when there are more processes than rows
+	offset := max(t.selected-height+1, 0)
+	for row := range rows {
+		i := row + offset
+		switch {
+		case i >= len(entries):
+			rows[row] = strings.Repeat(" ", width)
+		case i == t.selected && !t.showPorts:
+			rows[row] = "\x1b[1;7m" + supervisor.StripANSI(entries[i]) + "\x1b[0m"
+		default:
+			rows[row] = entries[i]
+		}
+	}
+	return rows
+}
+
+// logRows renders the filtered output, newest at the bottom. Must be called
+// with t.mu held.
+func (t *tui) logRows(width int, height int) []string {
+	var visible []supervisor.ProcessOutput
+	for _, line := range t.lines {
+		if t.matches(line) {
+			visible = append(visible, line)
+		}
+	}
+
+	t.scroll = min(t.scroll, max(len(visible)-height, 0))
+	end := len(visible) - t.scroll
+	start := max(end-height, 0)
+
+	rows := make([]string, height)
+	for row := range rows {
+		i := start + row
+		if i >= end {
+			rows[row] = strings.Repeat(" ", width)
+			continue
+		}
+		line := visible[i]
+		text := strings.ReplaceAll(supervisor.StripANSI(line.Line), "\t", "    ")
+
+		prefix := ""
+		prefixWidth := 0
+		if t.filter == "" {
+			prefixWidth = min(12, width/4)
+			prefix = fmt.Sprintf("\x1b[%sm%s\x1b[0m", t.colors[line.Name], fitWidth(" "+line.Name, prefixWidth))
+		}
+		text = fitWidth(" "+text, width-prefixWidth)
+		if line.IsStderr {
+			text = "\x1b[31m" + text + "\x1b[0m"
+		}
+		rows[row] = prefix + text
+	}
+	if t.scroll > 0 {
+		rows[height-1] = "\x1b[7m" + fitWidth(fmt.Sprintf(" ↓ %d more lines (End to follow)", t.scroll), width) + "\x1b[0m"
+	}
+	return rows
+}
+
+// portRows renders the ports panel. Must be called with t.mu held.
+func (t *tui) portRows(width int, height int) []string {
+	rows := make([]string, height)
+	rows[0] = "\x1b[1m" + fitWidth(fmt.Sprintf(" %-6s %-8s %-16s %s", "PORT", "PID", "PROCESS", "COMMAND"), width) + "\x1b[0m"
+	for row := 1; row < height; row++ {
+		i := row - 1
+		switch {
+		case i >= len(t.ports):
+			rows[row] = strings.Repeat(" ", width)
+			if i == 0 && len(t.ports) == 0 {
+				rows[row] = fitWidth(" No listening ports in 3000-9000", width)
+			}
+		default:
+			port := t.ports[i]
+			text := fitWidth(fmt.Sprintf(" %-6d %-8d %-16s %s", port.Port, port.PID, port.Process, port.Command), width)
+			if i == t.portSel {
+				text = "\x1b[7m" + text + "\x1b[0m"
+			}
+			rows[row] = text
+		}
+	}
+	return rows
+}
+
+// fitWidth cuts or pads s to exactly width columns (one per rune)
+func fitWidth(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	count := utf8.RuneCountInString(s)
+	if count <= width {
+		return s + strings.Repeat(" ", width-count)
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// readKeys reads key presses from r until it fails
+func readKeys(r io.Reader, keys chan<- string) {
+	buf := make([]byte, 256)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			keys <- key
+		}
+	}
+}
+
+// parseKeys turns raw terminal input into key names: printable characters
+// as themselves, plus "up", "down", "pgup", "pgdn", "home", "end", "esc",
+// "enter", "backspace" and "ctrl-<letter>"
+func parseKeys(data []byte) []string {
+	var keys []string
+	for len(data) > 0 {
+		switch b := data[0]; {
+		case b == 0x1b && len(data) > 2 && (data[1] == '[' || data[1] == 'O'):
+			// CSI or SS3 sequence: parameters, then a final byte
+			end := 2
+			for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+				end++
+			}
+			if end == len(data) {
+				return keys
+			}
+			switch string(data[2 : end+1]) {
+			case "A":
+				keys = append(keys, "up")
+			case "B":
+				keys = append(keys, "down")
+			case "H", "1~", "7~":
+				keys = append(keys, "home")
+			case "F", "4~", "8~":
+				keys = append(keys, "end")
+			case "5~":
+				keys = append(keys, "pgup")
+			case "6~":
+				keys = append(keys, "pgdn")
+			}
+			data = data[end+1:]
+		case b == 0x1b:
+			keys = append(keys, "esc")
+			data = data[1:]
+		case b == '\r' || b == '\n':
+			keys = append(keys, "enter")
+			data = data[1:]
+		case b == 0x7f || b == 0x08:
+			keys = append(keys, "backspace")
+			data = data[1:]
+		case b < 0x20:
+			keys = append(keys, "ctrl-"+string(rune('a'+b-1)))
+			data = data[1:]
+		default:
+			r, size := utf8.DecodeRune(data)
+			if r != utf8.RuneError {
+				keys = append(keys, string(r))
+			}
+			data = data[size:]
+		}
+	}
+	return keys
+}